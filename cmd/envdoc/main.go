@@ -0,0 +1,87 @@
+// Command envdoc renders the environment variables of a service as documentation.
+//
+// It reads a registry in JSON, as written by env.Registry.Write with env.DocJSON, and prints it in the requested
+// format, so that CI can diff the output against the committed documentation:
+//
+//	envdoc -in env.json -format markdown > docs/env.md
+//	envdoc -in env.json -format helm > chart/values.env.yaml
+//
+// The formats are markdown, dotenv, jsonschema, helm and json.
+//
+// A service produces the registry itself, for example behind a flag that registers its configuration struct and
+// writes it instead of starting:
+//
+//	printEnv := flag.Bool("print-env-registry", false, "print the environment variables as JSON and exit")
+//	flag.Parse()
+//	if *printEnv {
+//		var cfg Config
+//		if err := env.DefaultRegistry.RegisterStruct(&cfg); err != nil {
+//			log.Fatal(err)
+//		}
+//		if err := env.DefaultRegistry.Write(os.Stdout, env.DocJSON); err != nil {
+//			log.Fatal(err)
+//		}
+//		return
+//	}
+//
+// RegisterStruct does not read the environment, so this works without any variable set. Variables that are read
+// with the getters instead of Load are registered on their first lookup, so they only appear once the service has
+// read them:
+//
+//	service -print-env-registry > env.json
+package main
+
+/*
+Copyright 2023 UMH Systems GmbH
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/united-manufacturing-hub/umh-utils/env"
+)
+
+func main() {
+	in := flag.String("in", "-", "registry JSON file to read, - for stdin")
+	format := flag.String("format", string(env.DocMarkdown), "output format: markdown, dotenv, jsonschema, helm or json")
+	flag.Parse()
+
+	if err := run(*in, env.DocFormat(*format), os.Stdout); err != nil {
+		fmt.Fprintln(os.Stderr, "envdoc:", err)
+		os.Exit(1)
+	}
+}
+
+// run reads the registry from path and writes it to w in format.
+func run(path string, format env.DocFormat, w io.Writer) error {
+	var r io.Reader = os.Stdin
+	if path != "-" {
+		f, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		r = f
+	}
+
+	registry, err := env.ReadRegistry(r)
+	if err != nil {
+		return err
+	}
+	return registry.Write(w, format)
+}
@@ -0,0 +1,78 @@
+package main
+
+/*
+Copyright 2023 UMH Systems GmbH
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/united-manufacturing-hub/umh-utils/env"
+)
+
+// TestRun tests rendering a registry file in the supported formats
+func TestRun(t *testing.T) {
+	t.Parallel()
+
+	type config struct {
+		Brokers string `env:"KAFKA_BROKERS,required" desc:"Kafka brokers"`
+		Port    int    `env:"PORT" default:"8080"`
+	}
+	registry := env.NewRegistry()
+	if err := registry.RegisterStruct(&config{}); err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err := registry.Write(&buf, env.DocJSON); err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "env.json")
+	if err := os.WriteFile(path, buf.Bytes(), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		path    string
+		format  env.DocFormat
+		want    string
+		wantErr bool
+	}{
+		{name: "Case 1: Markdown", path: path, format: env.DocMarkdown, want: "| `KAFKA_BROKERS` | string |  | yes | Kafka brokers |"},
+		{name: "Case 2: Dotenv", path: path, format: env.DocDotenv, want: "PORT=8080"},
+		{name: "Case 3: JSON round trip", path: path, format: env.DocJSON, want: buf.String()},
+		{name: "Case 4: Missing file", path: filepath.Join(t.TempDir(), "missing.json"), format: env.DocMarkdown, wantErr: true},
+		{name: "Case 5: Unknown format", path: path, format: "html", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			var out bytes.Buffer
+			err := run(tt.path, tt.format, &out)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("run() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !strings.Contains(out.String(), tt.want) {
+				t.Errorf("run() =\n%s\nwant it to contain\n%s", out.String(), tt.want)
+			}
+		})
+	}
+}
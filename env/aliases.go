@@ -0,0 +1,76 @@
+package env
+
+/*
+Copyright 2023 UMH Systems GmbH
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+import (
+	"strings"
+)
+
+// deprecatedTag lists the former names of a field, e.g. `deprecated:"MQTT_BROKER_URL"`.
+const deprecatedTag = "deprecated"
+
+// Alias is another name of a variable. Aliases are only read if the variable itself is not set.
+type Alias struct {
+	// Key is the name of the alias.
+	Key string
+	// Note is added to the deprecation warning, e.g. "renamed in v0.9.0".
+	Note string
+	// Deprecated logs a warning, once per Reader, when the value is read from the alias.
+	Deprecated bool
+}
+
+// WithAliases adds names that are looked up in order if the variable is not set, so that a renamed variable can
+// still be read from its former names:
+//
+//	env.GetAsString("MQTT_BROKER", true, "", env.WithAliases(env.Alias{Key: "MQTT_BROKER_URL", Deprecated: true}))
+func WithAliases(aliases ...Alias) Option {
+	return func(o *options) {
+		o.aliases = append(o.aliases, aliases...)
+	}
+}
+
+// Deprecated adds deprecated aliases, see WithAliases.
+func Deprecated(keys ...string) Option {
+	aliases := make([]Alias, 0, len(keys))
+	for _, key := range keys {
+		if key = strings.TrimSpace(key); key != "" {
+			aliases = append(aliases, Alias{Key: key, Deprecated: true})
+		}
+	}
+	return WithAliases(aliases...)
+}
+
+// aliasUse is a deprecated alias read instead of a variable, which is warned about once per Reader.
+type aliasUse struct {
+	key   string
+	alias string
+}
+
+// warnAlias logs that the deprecated alias has been read instead of key, unless r has logged this before.
+func (r *Reader) warnAlias(key string, alias Alias, o *options) {
+	if !alias.Deprecated {
+		return
+	}
+	if _, warned := r.warnedAliases.LoadOrStore(aliasUse{key: key, alias: alias.Key}, true); warned {
+		return
+	}
+	keysAndValues := []any{"key", alias.Key, "replacement", key}
+	if alias.Note != "" {
+		keysAndValues = append(keysAndValues, "note", alias.Note)
+	}
+	o.warn("Deprecated environment variable is used, please rename it", keysAndValues...)
+}
@@ -0,0 +1,137 @@
+package env
+
+/*
+Copyright 2023 UMH Systems GmbH
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+// TestAliases tests that aliases are read in order when the variable itself is not set
+func TestAliases(t *testing.T) {
+	t.Parallel()
+
+	r := NewReader(MapSource{
+		"ALIAS_NEW":        "new",
+		"ALIAS_OLD":        "old",
+		"ALIAS_OLDER":      "older",
+		"ALIAS_ONLY_OLD":   "old",
+		"ALIAS_ONLY_OLDER": "older",
+		"ALIAS_EMPTY":      "",
+	})
+	tests := []struct {
+		name     string
+		key      string
+		want     string
+		opts     []Option
+		required bool
+		wantErr  bool
+	}{
+		{name: "Case 1: The new name wins", key: "ALIAS_NEW", opts: []Option{Deprecated("ALIAS_OLD")}, want: "new"},
+		{name: "Case 2: The deprecated name is used", key: "ALIAS_UNSET", opts: []Option{Deprecated("ALIAS_OLD")}, want: "old"},
+		{name: "Case 3: Aliases are read in order", key: "ALIAS_UNSET", opts: []Option{Deprecated("ALIAS_ONLY_OLDER", "ALIAS_ONLY_OLD")}, want: "older"},
+		{name: "Case 4: Unset aliases are skipped", key: "ALIAS_UNSET", opts: []Option{Deprecated("ALIAS_NONEXISTENT", "ALIAS_OLDER")}, want: "older"},
+		{name: "Case 5: No alias set falls back", key: "ALIAS_UNSET", opts: []Option{Deprecated("ALIAS_NONEXISTENT")}, want: "fallback"},
+		{name: "Case 6: No alias set fails if required", key: "ALIAS_UNSET", opts: []Option{Deprecated("ALIAS_NONEXISTENT")}, required: true, wantErr: true},
+		{name: "Case 7: Empty values skip to the alias with EmptyAsUnset", key: "ALIAS_EMPTY", opts: []Option{Deprecated("ALIAS_OLD"), EmptyAsUnset()}, want: "old"},
+		{name: "Case 8: Empty values win without EmptyAsUnset", key: "ALIAS_EMPTY", opts: []Option{Deprecated("ALIAS_OLD")}, want: ""},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			got, err := r.GetAsString(tt.key, tt.required, "fallback", append(tt.opts, WithLogger(zap.NewNop().Sugar()))...)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("GetAsString() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && got != tt.want {
+				t.Errorf("GetAsString() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestAliasWarning tests that a deprecated alias is warned about once
+func TestAliasWarning(t *testing.T) {
+	t.Parallel()
+
+	core, logs := observer.New(zapcore.WarnLevel)
+	r := NewReader(MapSource{"ALIAS_WARN_OLD": "1883", "ALIAS_WARN_OTHER": "x"}, WithLogger(zap.New(core).Sugar()))
+	for i := 0; i < 3; i++ {
+		if got, err := r.GetAsInt("ALIAS_WARN_NEW", true, 0, WithAliases(Alias{Key: "ALIAS_WARN_OLD", Deprecated: true, Note: "renamed in v0.9.0"})); err != nil || got != 1883 {
+			t.Fatalf("GetAsInt() = %d, %v", got, err)
+		}
+	}
+	if _, err := r.GetAsString("ALIAS_WARN_NEW", true, "", WithAliases(Alias{Key: "ALIAS_WARN_OTHER"})); err != nil {
+		t.Fatalf("GetAsString() error = %v", err)
+	}
+
+	entries := logs.All()
+	if len(entries) != 1 {
+		t.Fatalf("got %d warnings, want 1", len(entries))
+	}
+	fields := entries[0].ContextMap()
+	if fields["key"] != "ALIAS_WARN_OLD" || fields["replacement"] != "ALIAS_WARN_NEW" || fields["note"] != "renamed in v0.9.0" {
+		t.Errorf("warning fields = %v", fields)
+	}
+	if got := r.Snapshot(); len(got) != 1 || got[0].Alias != "ALIAS_WARN_OTHER" {
+		t.Errorf("Snapshot() = %v, want the alias to be recorded", got)
+	}
+	// Another reader warns on its own
+	other := NewReader(MapSource{"ALIAS_WARN_OLD": "1883"}, WithLogger(zap.New(core).Sugar()))
+	if _, err := other.GetAsInt("ALIAS_WARN_NEW", true, 0, Deprecated("ALIAS_WARN_OLD")); err != nil {
+		t.Fatalf("GetAsInt() error = %v", err)
+	}
+	if got := logs.Len(); got != 2 {
+		t.Errorf("got %d warnings after a second reader, want 2", got)
+	}
+}
+
+// TestAliasLoadAndDocs tests the deprecated tag and that the documentation lists former names
+func TestAliasLoadAndDocs(t *testing.T) {
+	t.Parallel()
+
+	reg := NewRegistry()
+	r := NewReader(MapSource{"MQTT_BROKER_URL": "tcp://localhost:1883"}, WithRegistry(reg), WithLogger(zap.NewNop().Sugar()))
+	var cfg struct {
+		Broker string `env:"MQTT_BROKER,required" deprecated:"MQTT_BROKER_URL, MQTT_URL" desc:"MQTT broker"`
+	}
+	if err := r.Load(&cfg); err != nil || cfg.Broker != "tcp://localhost:1883" {
+		t.Fatalf("Load() = %+v, %v", cfg, err)
+	}
+
+	var buf bytes.Buffer
+	if err := reg.WriteMarkdown(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if want := "| `MQTT_BROKER` | string |  | yes | MQTT broker Deprecated: MQTT_BROKER_URL, MQTT_URL. |"; !strings.Contains(buf.String(), want) {
+		t.Errorf("WriteMarkdown() =\n%s\nwant it to contain\n%s", buf.String(), want)
+	}
+	buf.Reset()
+	if err := reg.WriteJSONSchema(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if want := `"description": "Deprecated, use MQTT_BROKER instead.",`; !strings.Contains(buf.String(), want) {
+		t.Errorf("WriteJSONSchema() =\n%s\nwant it to contain %s", buf.String(), want)
+	}
+}
@@ -0,0 +1,100 @@
+package env
+
+/*
+Copyright 2023 UMH Systems GmbH
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+var (
+	// defaultTrueWords and defaultFalseWords are the boolean grammar, matched case-insensitively.
+	defaultTrueWords  = []string{"true", "yes", "y", "on", "enabled", "1"}
+	defaultFalseWords = []string{"false", "no", "n", "off", "disabled", "0"}
+)
+
+// StrictBool makes boolean values accept only the words of the grammar, rejecting the other forms that
+// strconv.ParseBool understands, such as "t" or "F".
+func StrictBool() Option {
+	return func(o *options) {
+		o.strictBool = true
+	}
+}
+
+// BoolWords replaces the words of the boolean grammar, which are matched case-insensitively. A word in both lists
+// is rejected as ambiguous.
+func BoolWords(trueWords, falseWords []string) Option {
+	return func(o *options) {
+		o.trueWords, o.falseWords = trueWords, falseWords
+	}
+}
+
+// parseBool parses raw with the boolean grammar of o, falling back to strconv.ParseBool unless StrictBool is set.
+func parseBool(raw string, o *options) (bool, error) {
+	trueWords, falseWords := o.trueWords, o.falseWords
+	if trueWords == nil && falseWords == nil {
+		trueWords, falseWords = defaultTrueWords, defaultFalseWords
+	}
+
+	isTrue, isFalse := containsFold(trueWords, raw), containsFold(falseWords, raw)
+	switch {
+	case isTrue && isFalse:
+		return false, fmt.Errorf("%q is ambiguous, it is both a true and a false word", raw)
+	case isTrue:
+		return true, nil
+	case isFalse:
+		return false, nil
+	}
+
+	if !o.strictBool {
+		if b, err := strconv.ParseBool(raw); err == nil {
+			return b, nil
+		}
+	}
+	return false, errors.New("expected " + describeWords(trueWords, falseWords))
+}
+
+// containsFold reports whether words contains s, ignoring case.
+func containsFold(words []string, s string) bool {
+	for _, word := range words {
+		if strings.EqualFold(word, s) {
+			return true
+		}
+	}
+	return false
+}
+
+// describeWords lists the words of a boolean grammar in pairs, e.g. "true/false, yes/no or on/off".
+func describeWords(trueWords, falseWords []string) string {
+	var pairs []string
+	for i := 0; i < len(trueWords) || i < len(falseWords); i++ {
+		var pair []string
+		if i < len(trueWords) {
+			pair = append(pair, trueWords[i])
+		}
+		if i < len(falseWords) {
+			pair = append(pair, falseWords[i])
+		}
+		pairs = append(pairs, strings.Join(pair, "/"))
+	}
+	if len(pairs) < 2 {
+		return strings.Join(pairs, "")
+	}
+	return strings.Join(pairs[:len(pairs)-1], ", ") + " or " + pairs[len(pairs)-1]
+}
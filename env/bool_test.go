@@ -0,0 +1,95 @@
+package env
+
+/*
+Copyright 2023 UMH Systems GmbH
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// TestBoolGrammar tests the words accepted as booleans, with and without StrictBool
+func TestBoolGrammar(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		value   string
+		opts    []Option
+		want    bool
+		wantErr string
+	}{
+		{name: "Case 1: yes", value: "yes", want: true},
+		{name: "Case 2: Case is ignored", value: "No", want: false},
+		{name: "Case 3: on", value: "ON", want: true},
+		{name: "Case 4: off", value: "off", want: false},
+		{name: "Case 5: enabled", value: "Enabled", want: true},
+		{name: "Case 6: disabled", value: "DISABLED", want: false},
+		{name: "Case 7: y", value: "Y", want: true},
+		{name: "Case 8: n", value: "n", want: false},
+		{name: "Case 9: strconv forms are accepted by default", value: "t", want: true},
+		{name: "Case 10: strconv forms are rejected when strict", value: "t", opts: []Option{StrictBool()}, wantErr: "expected true/false, yes/no, y/n, on/off, enabled/disabled or 1/0"},
+		{name: "Case 11: Grammar words are accepted when strict", value: "TRUE", opts: []Option{StrictBool()}, want: true},
+		{name: "Case 12: Unknown words are rejected", value: "maybe", wantErr: "expected true/false"},
+		{name: "Case 13: Custom words", value: "aktiv", opts: []Option{BoolWords([]string{"aktiv"}, []string{"inaktiv"}), StrictBool()}, want: true},
+		{name: "Case 14: Custom words replace the grammar", value: "yes", opts: []Option{BoolWords([]string{"aktiv"}, []string{"inaktiv"}), StrictBool()}, wantErr: "expected aktiv/inaktiv"},
+		{name: "Case 15: Words in both lists are ambiguous", value: "x", opts: []Option{BoolWords([]string{"x", "1"}, []string{"X"})}, wantErr: "ambiguous"},
+		{name: "Case 16: Surrounding space is not accepted", value: " yes", wantErr: "expected"},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			got, err := NewReader(MapSource{"BOOL": tt.value}).GetAsBool("BOOL", true, !tt.want, tt.opts...)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("GetAsBool() error = %v, want it to contain %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil || got != tt.want {
+				t.Errorf("GetAsBool() = %v, %v, want %v", got, err, tt.want)
+			}
+		})
+	}
+}
+
+// TestBoolGrammarEverywhere tests that the grammar applies to GetAs, collections and Load
+func TestBoolGrammarEverywhere(t *testing.T) {
+	t.Parallel()
+
+	r := NewReader(MapSource{"BOOL_ON": "on", "BOOL_LIST": "yes,no,enabled", "BOOL_T": "T", "BOOL_PTR": "disabled"})
+	if got, err := GetAsFrom(r, "BOOL_ON", true, false); err != nil || !got {
+		t.Errorf("GetAsFrom() = %v, %v", got, err)
+	}
+	if got, err := GetAsSliceFrom[bool](r, "BOOL_LIST", true, nil); err != nil || !reflect.DeepEqual(got, []bool{true, false, true}) {
+		t.Errorf("GetAsSliceFrom() = %v, %v", got, err)
+	}
+
+	var cfg struct {
+		On     bool  `env:"BOOL_ON"`
+		Ptr    *bool `env:"BOOL_PTR"`
+		Strict bool  `env:"BOOL_T,strictbool"`
+	}
+	err := r.Load(&cfg)
+	var errs Errors
+	if !cfg.On || cfg.Ptr == nil || *cfg.Ptr || !errors.As(err, &errs) || len(errs) != 1 || !strings.Contains(errs[0].Error(), "BOOL_T") {
+		t.Errorf("Load() = %+v, %v, want only BOOL_T to fail", cfg, err)
+	}
+}
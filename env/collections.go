@@ -0,0 +1,134 @@
+package env
+
+/*
+Copyright 2023 UMH Systems GmbH
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// GetAsSlice returns the value of the environment variable as a list, such as "a,b,c". The elements are split on
+// the Separator option, which defaults to ",", trimmed and parsed with the same rules as the scalar getters. Empty
+// elements are skipped. If an element cannot be parsed, the returned *ParseError wraps an *ElementError holding its
+// index. If the environment variable is not set and not required, the fallback value is returned.
+func GetAsSlice[T any](key string, required bool, fallback []T, opts ...Option) ([]T, error) {
+	return GetAsSliceFrom(defaultReader, key, required, fallback, opts...)
+}
+
+// GetAsSliceFrom is GetAsSlice, reading from the source of r.
+func GetAsSliceFrom[T any](r *Reader, key string, required bool, fallback []T, opts ...Option) ([]T, error) {
+	return get(r, key, required, fallback, append(opts[:len(opts):len(opts)], splitting()), func(value string, o *options) ([]T, error) {
+		list, err := parseList(reflect.TypeOf(fallback), value, o)
+		if err != nil {
+			return nil, err
+		}
+		return list.Interface().([]T), nil
+	})
+}
+
+// GetAsMap returns the value of the environment variable as a map, such as "k1=v1,k2=v2". The pairs are split on
+// the Separator option, which defaults to ",", and each pair on the first KeyValueSeparator, which defaults to "=".
+// Keys and values are trimmed and parsed with the same rules as the scalar getters. Empty pairs are skipped; if a
+// key appears twice, the last value wins. If a pair cannot be parsed, the returned *ParseError wraps an
+// *ElementError holding its index. If the environment variable is not set and not required, the fallback value is
+// returned.
+func GetAsMap[K comparable, V any](key string, required bool, fallback map[K]V, opts ...Option) (map[K]V, error) {
+	return GetAsMapFrom(defaultReader, key, required, fallback, opts...)
+}
+
+// GetAsMapFrom is GetAsMap, reading from the source of r.
+func GetAsMapFrom[K comparable, V any](r *Reader, key string, required bool, fallback map[K]V, opts ...Option) (map[K]V, error) {
+	return get(r, key, required, fallback, append(opts[:len(opts):len(opts)], splitting()), func(value string, o *options) (map[K]V, error) {
+		m, err := parseMap(reflect.TypeOf(fallback), value, o)
+		if err != nil {
+			return nil, err
+		}
+		return m.Interface().(map[K]V), nil
+	})
+}
+
+// parseList parses value into a slice of type t, as described by GetAsSlice.
+func parseList(t reflect.Type, value string, o *options) (reflect.Value, error) {
+	elements := splitElements(value, o.listSeparator())
+	list := reflect.MakeSlice(t, 0, len(elements))
+	for i, element := range elements {
+		if element == "" {
+			continue
+		}
+		v := reflect.New(t.Elem()).Elem()
+		if err := parseValue(v, element, o); err != nil {
+			return reflect.Value{}, newElementError(i, element, err)
+		}
+		list = reflect.Append(list, v)
+	}
+	return list, nil
+}
+
+// parseMap parses value into a map of type t, as described by GetAsMap.
+func parseMap(t reflect.Type, value string, o *options) (reflect.Value, error) {
+	elements := splitElements(value, o.listSeparator())
+	kvSep := o.pairSeparator()
+	m := reflect.MakeMapWithSize(t, len(elements))
+	for i, element := range elements {
+		if element == "" {
+			continue
+		}
+		rawKey, rawValue, found := strings.Cut(element, kvSep)
+		if !found {
+			return reflect.Value{}, newElementError(i, element, fmt.Errorf("missing %q between key and value", kvSep))
+		}
+
+		k := reflect.New(t.Key()).Elem()
+		if err := parseValue(k, strings.TrimSpace(rawKey), o); err != nil {
+			return reflect.Value{}, newElementError(i, element, fmt.Errorf("invalid key: %w", plainError(err)))
+		}
+		v := reflect.New(t.Elem()).Elem()
+		if err := parseValue(v, strings.TrimSpace(rawValue), o); err != nil {
+			return reflect.Value{}, newElementError(i, element, fmt.Errorf("invalid value: %w", plainError(err)))
+		}
+		m.SetMapIndex(k, v)
+	}
+	return m, nil
+}
+
+// listSeparator returns the separator between the elements of lists and maps. An empty Separator means ",".
+func (o *options) listSeparator() string {
+	if o.separator == "" {
+		return ","
+	}
+	return o.separator
+}
+
+// pairSeparator returns the separator between the key and the value of map elements. An empty KeyValueSeparator
+// means "=".
+func (o *options) pairSeparator() string {
+	if o.keyValueSeparator == "" {
+		return "="
+	}
+	return o.keyValueSeparator
+}
+
+// splitElements splits value on sep and trims the elements. Empty elements are kept, so that the index of an
+// element matches its position in value.
+func splitElements(value, sep string) []string {
+	elements := strings.Split(value, sep)
+	for i := range elements {
+		elements[i] = strings.TrimSpace(elements[i])
+	}
+	return elements
+}
@@ -0,0 +1,103 @@
+package env
+
+/*
+Copyright 2023 UMH Systems GmbH
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// TestGetAsSlice tests the GetAsSlice function
+func TestGetAsSlice(t *testing.T) {
+	t.Parallel()
+
+	r := NewReader(MapSource{
+		"BROKERS":   " kafka-0:9092, kafka-1:9092 ,kafka-2:9092,",
+		"TOPICS":    "a;b;c",
+		"PORTS":     "1883,8883",
+		"WRONG":     "1,two,3",
+		"DURATIONS": "1s,500ms,2",
+		"EMPTY":     "",
+	})
+
+	if got, err := GetAsSliceFrom[string](r, "BROKERS", true, nil); err != nil || !reflect.DeepEqual(got, []string{"kafka-0:9092", "kafka-1:9092", "kafka-2:9092"}) {
+		t.Errorf("GetAsSlice() = %q, %v", got, err)
+	}
+	if got, err := GetAsSliceFrom[string](r, "TOPICS", true, nil, Separator(";")); err != nil || !reflect.DeepEqual(got, []string{"a", "b", "c"}) {
+		t.Errorf("GetAsSlice() with separator = %q, %v", got, err)
+	}
+	if got, err := GetAsSliceFrom[int](r, "PORTS", true, nil); err != nil || !reflect.DeepEqual(got, []int{1883, 8883}) {
+		t.Errorf("GetAsSlice() = %v, %v", got, err)
+	}
+	if got, err := GetAsSliceFrom[time.Duration](r, "DURATIONS", true, nil); err != nil || !reflect.DeepEqual(got, []time.Duration{time.Second, 500 * time.Millisecond, 2 * time.Second}) {
+		t.Errorf("GetAsSlice() = %v, %v", got, err)
+	}
+	if got, err := GetAsSliceFrom[string](r, "EMPTY", true, nil); err != nil || len(got) != 0 {
+		t.Errorf("GetAsSlice() = %q, %v, want empty list", got, err)
+	}
+	if got, err := GetAsSliceFrom(r, "NONEXISTENT", false, []int{1}); err != nil || !reflect.DeepEqual(got, []int{1}) {
+		t.Errorf("GetAsSlice() = %v, %v, want fallback", got, err)
+	}
+	if _, err := GetAsSliceFrom[int](r, "NONEXISTENT", true, nil); !errors.Is(err, ErrNotSet) {
+		t.Errorf("GetAsSlice() error = %v, want ErrNotSet", err)
+	}
+
+	got, err := GetAsSliceFrom(r, "WRONG", false, []int{42})
+	var elementErr *ElementError
+	if !errors.As(err, &elementErr) || elementErr.Index != 1 || elementErr.Value != "two" {
+		t.Errorf("GetAsSlice() error = %v, want *ElementError for index 1", err)
+	}
+	if !reflect.DeepEqual(got, []int{42}) {
+		t.Errorf("GetAsSlice() = %v, want fallback", got)
+	}
+}
+
+// TestGetAsMap tests the GetAsMap function
+func TestGetAsMap(t *testing.T) {
+	t.Parallel()
+
+	r := NewReader(MapSource{
+		"LABELS":  "site = aachen, line=1,",
+		"CUSTOM":  "k1:v1;k2:v2",
+		"LIMITS":  "cpu=2,memory=4",
+		"NO_SEP":  "a=1,b",
+		"BAD_KEY": "1=a,x=b",
+	})
+
+	if got, err := GetAsMapFrom[string, string](r, "LABELS", true, nil); err != nil || !reflect.DeepEqual(got, map[string]string{"site": "aachen", "line": "1"}) {
+		t.Errorf("GetAsMap() = %v, %v", got, err)
+	}
+	if got, err := GetAsMapFrom[string, string](r, "CUSTOM", true, nil, Separator(";"), KeyValueSeparator(":")); err != nil || !reflect.DeepEqual(got, map[string]string{"k1": "v1", "k2": "v2"}) {
+		t.Errorf("GetAsMap() with separators = %v, %v", got, err)
+	}
+	if got, err := GetAsMapFrom[string, int](r, "LIMITS", true, nil); err != nil || !reflect.DeepEqual(got, map[string]int{"cpu": 2, "memory": 4}) {
+		t.Errorf("GetAsMap() = %v, %v", got, err)
+	}
+	if got, err := GetAsMapFrom[string, int](r, "LIMITS", true, nil, Separator(""), KeyValueSeparator("")); err != nil || !reflect.DeepEqual(got, map[string]int{"cpu": 2, "memory": 4}) {
+		t.Errorf("GetAsMap() with empty separators = %v, %v", got, err)
+	}
+
+	var elementErr *ElementError
+	if _, err := GetAsMapFrom[string, int](r, "NO_SEP", false, nil); !errors.As(err, &elementErr) || elementErr.Index != 1 {
+		t.Errorf("GetAsMap() error = %v, want *ElementError for index 1", err)
+	}
+	if _, err := GetAsMapFrom[int, string](r, "BAD_KEY", false, nil); !errors.As(err, &elementErr) || elementErr.Index != 1 {
+		t.Errorf("GetAsMap() error = %v, want *ElementError for index 1", err)
+	}
+}
@@ -0,0 +1,459 @@
+package env
+
+/*
+Copyright 2023 UMH Systems GmbH
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+import (
+	"bufio"
+	"encoding"
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// descTag holds the description of a field, e.g. `desc:"Comma-separated list of Kafka brokers"`.
+const descTag = "desc"
+
+// DocFormat is an output format of Registry.Write.
+type DocFormat string
+
+const (
+	DocMarkdown   = DocFormat("markdown")   // a Markdown table for READMEs
+	DocDotenv     = DocFormat("dotenv")     // a sample .env file
+	DocJSONSchema = DocFormat("jsonschema") // a JSON Schema of all variables
+	DocHelm       = DocFormat("helm")       // a Helm values.yaml fragment
+	DocJSON       = DocFormat("json")       // the registry itself, readable by ReadRegistry
+)
+
+// Var describes an environment variable.
+type Var struct {
+	// Key is the name of the variable.
+	Key string `json:"key"`
+	// Type is the Go type the value is parsed into.
+	Type string `json:"type"`
+	// Default is the fallback value, formatted as it would be written in the environment.
+	Default string `json:"default,omitempty"`
+	// Description explains the variable.
+	Description string `json:"description,omitempty"`
+	// Required is set if the variable must be set.
+	Required bool `json:"required,omitempty"`
+	// Sensitive is set for secrets; their default is never rendered.
+	Sensitive bool `json:"sensitive,omitempty"`
+	// Aliases are other names the variable is read from.
+	Aliases []string `json:"aliases,omitempty"`
+	// Deprecated are former names the variable is still read from.
+	Deprecated []string `json:"deprecated,omitempty"`
+}
+
+// Registry collects the variables a service reads, so they can be documented. Every lookup registers its variable
+// in the Registry of its options, which is DefaultRegistry unless WithRegistry is used.
+type Registry struct {
+	vars map[string]Var
+	mu   sync.Mutex
+}
+
+// DefaultRegistry collects all variables that have been looked up without a WithRegistry option.
+var DefaultRegistry = NewRegistry()
+
+// NewRegistry returns an empty Registry.
+func NewRegistry() *Registry {
+	return &Registry{vars: make(map[string]Var)}
+}
+
+// Register adds v, or merges it into the variable with the same key: an empty description does not replace an
+// existing one, aliases and deprecated names are added to the existing ones, and a variable stays sensitive once
+// it has been registered as such.
+func (r *Registry) Register(v Var) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if old, ok := r.vars[v.Key]; ok {
+		if v.Description == "" {
+			v.Description = old.Description
+		}
+		v.Aliases = appendMissing(old.Aliases, v.Aliases)
+		v.Deprecated = appendMissing(old.Deprecated, v.Deprecated)
+		v.Sensitive = v.Sensitive || old.Sensitive
+	}
+	r.vars[v.Key] = v
+}
+
+// appendMissing returns a new slice with the names of list followed by those of add that it does not contain yet.
+func appendMissing(list, add []string) []string {
+	if len(add) == 0 {
+		return list
+	}
+	merged := append([]string(nil), list...)
+	for _, name := range add {
+		if !containsString(merged, name) {
+			merged = append(merged, name)
+		}
+	}
+	return merged
+}
+
+// containsString reports whether list contains s.
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
+// RegisterStruct registers the variables of the struct pointed to by cfg, as described by the tags that Load
+// understands, without reading the environment. The `desc:"..."` tag adds a description. The current values of the
+// fields serve as defaults where there is no default tag; cfg itself is not modified.
+func (r *Registry) RegisterStruct(cfg any) error {
+	v := reflect.ValueOf(cfg)
+	if v.Kind() != reflect.Pointer || v.IsNil() || v.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("env.RegisterStruct expects a non-nil pointer to a struct, got %T", cfg)
+	}
+	clone := reflect.New(v.Elem().Type())
+	clone.Elem().Set(v.Elem())
+
+	reader := NewReader(MapSource{}, WithRegistry(r))
+	return loadStruct(clone.Elem(), reader.NewLoader())
+}
+
+// Vars returns all registered variables, sorted by key.
+func (r *Registry) Vars() []Var {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	vars := make([]Var, 0, len(r.vars))
+	for _, v := range r.vars {
+		vars = append(vars, v)
+	}
+	sort.Slice(vars, func(i, j int) bool {
+		return vars[i].Key < vars[j].Key
+	})
+	return vars
+}
+
+// ReadRegistry reads a registry written in the DocJSON format.
+func ReadRegistry(rd io.Reader) (*Registry, error) {
+	var vars []Var
+	if err := json.NewDecoder(rd).Decode(&vars); err != nil {
+		return nil, fmt.Errorf("failed to decode registry: %w", err)
+	}
+	r := NewRegistry()
+	for _, v := range vars {
+		r.Register(v)
+	}
+	return r, nil
+}
+
+// Write renders all registered variables in the given format.
+func (r *Registry) Write(w io.Writer, format DocFormat) error {
+	switch format {
+	case DocMarkdown:
+		return r.WriteMarkdown(w)
+	case DocDotenv:
+		return r.WriteDotenv(w)
+	case DocJSONSchema:
+		return r.WriteJSONSchema(w)
+	case DocHelm:
+		return r.WriteHelmValues(w)
+	case DocJSON:
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(r.Vars())
+	default:
+		return fmt.Errorf("unknown documentation format %q", format)
+	}
+}
+
+// WriteMarkdown renders the variables as a Markdown table.
+func (r *Registry) WriteMarkdown(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	fmt.Fprint(bw, "| Variable | Type | Default | Required | Description |\n")
+	fmt.Fprint(bw, "|----------|------|---------|----------|-------------|\n")
+	for _, v := range r.Vars() {
+		def := ""
+		if d := v.documentedDefault(); d != "" {
+			def = "`" + markdownCell(d) + "`"
+		}
+		required := "no"
+		if v.Required {
+			required = "yes"
+		}
+		desc := markdownCell(v.Description)
+		if names := v.otherNames(); names != "" {
+			desc = strings.TrimSpace(desc + " " + markdownCell(names))
+		}
+		fmt.Fprintf(bw, "| `%s` | %s | %s | %s | %s |\n", v.Key, markdownCell(v.Type), def, required, desc)
+	}
+	return bw.Flush()
+}
+
+// WriteDotenv renders the variables as a sample .env file, with the description as a comment.
+func (r *Registry) WriteDotenv(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	for i, v := range r.Vars() {
+		if i > 0 {
+			fmt.Fprint(bw, "\n")
+		}
+		fmt.Fprintf(bw, "# %s\n", v.summary())
+		fmt.Fprintf(bw, "%s=%s\n", v.Key, dotenvQuote(v.documentedDefault()))
+	}
+	return bw.Flush()
+}
+
+// WriteJSONSchema renders the variables as the JSON Schema of an object holding the environment.
+func (r *Registry) WriteJSONSchema(w io.Writer) error {
+	type property struct {
+		Default     any    `json:"default,omitempty"`
+		Type        string `json:"type"`
+		Description string `json:"description,omitempty"`
+		WriteOnly   bool   `json:"writeOnly,omitempty"`
+		Deprecated  bool   `json:"deprecated,omitempty"`
+	}
+	schema := struct {
+		Properties map[string]property `json:"properties"`
+		Schema     string              `json:"$schema"`
+		Type       string              `json:"type"`
+		Required   []string            `json:"required,omitempty"`
+	}{
+		Schema:     "https://json-schema.org/draft/2020-12/schema",
+		Type:       "object",
+		Properties: make(map[string]property),
+	}
+	for _, v := range r.Vars() {
+		p := property{Type: v.jsonType(), Description: v.Description, WriteOnly: v.Sensitive}
+		if def := v.documentedDefault(); def != "" {
+			p.Default = def
+			if p.Type != "string" {
+				var typed any
+				if err := json.Unmarshal([]byte(def), &typed); err == nil {
+					p.Default = typed
+				}
+			}
+		}
+		schema.Properties[v.Key] = p
+		for _, name := range v.Aliases {
+			alias := p
+			alias.Description = "Alias of " + v.Key + "."
+			schema.Properties[name] = alias
+		}
+		for _, name := range v.Deprecated {
+			old := p
+			old.Description = "Deprecated, use " + v.Key + " instead."
+			old.Deprecated = true
+			schema.Properties[name] = old
+		}
+		if v.Required {
+			schema.Required = append(schema.Required, v.Key)
+		}
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(schema)
+}
+
+// WriteHelmValues renders the variables as a Helm values.yaml fragment below an "env" key.
+func (r *Registry) WriteHelmValues(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	fmt.Fprint(bw, "env:\n")
+	for _, v := range r.Vars() {
+		fmt.Fprintf(bw, "  # %s\n", v.summary())
+		fmt.Fprintf(bw, "  %s: %s\n", v.Key, strconv.Quote(v.documentedDefault()))
+	}
+	return bw.Flush()
+}
+
+// documentedDefault returns the default, unless the variable is sensitive.
+func (v Var) documentedDefault() string {
+	if v.Sensitive {
+		return ""
+	}
+	return v.Default
+}
+
+// summary describes v in one line for comments.
+func (v Var) summary() string {
+	var b strings.Builder
+	if v.Description != "" {
+		b.WriteString(v.Description)
+		b.WriteString(" ")
+	}
+	b.WriteString("(")
+	b.WriteString(v.Type)
+	if v.Required {
+		b.WriteString(", required")
+	}
+	if v.Sensitive {
+		b.WriteString(", sensitive")
+	}
+	b.WriteString(")")
+	if names := v.otherNames(); names != "" {
+		b.WriteString(" ")
+		b.WriteString(names)
+	}
+	return b.String()
+}
+
+// otherNames lists the aliases and deprecated names of v, e.g. "Deprecated: MQTT_BROKER_URL.".
+func (v Var) otherNames() string {
+	var parts []string
+	if len(v.Aliases) > 0 {
+		parts = append(parts, "Aliases: "+strings.Join(v.Aliases, ", ")+".")
+	}
+	if len(v.Deprecated) > 0 {
+		parts = append(parts, "Deprecated: "+strings.Join(v.Deprecated, ", ")+".")
+	}
+	return strings.Join(parts, " ")
+}
+
+// jsonType maps the Go type of v to a JSON Schema type.
+func (v Var) jsonType() string {
+	switch v.Type {
+	case "int", "int8", "int16", "int32", "int64", "uint", "uint8", "uint16", "uint32", "uint64":
+		return "integer"
+	case "float32", "float64":
+		return "number"
+	case "bool":
+		return "boolean"
+	default:
+		return "string"
+	}
+}
+
+// markdownCell escapes s for use in a table cell.
+func markdownCell(s string) string {
+	s = strings.ReplaceAll(s, "|", `\|`)
+	return strings.ReplaceAll(s, "\n", " ")
+}
+
+// dotenvQuote quotes s if it would not survive LoadDotenv unquoted.
+func dotenvQuote(s string) string {
+	if s == "" || !strings.ContainsAny(s, " \t\n\r#'\"\\$") {
+		return s
+	}
+	r := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`, "\r", `\r`, "\t", `\t`, "$", `\$`)
+	return `"` + r.Replace(s) + `"`
+}
+
+// register records key in the registry of o. The default of sensitive and required variables is left out.
+func (o *options) register(key, typ string, required bool, fallback any) {
+	reg := o.registry
+	if reg == nil {
+		reg = DefaultRegistry
+	}
+	v := Var{Key: key, Type: typ, Description: o.description, Required: required, Sensitive: o.sensitive}
+	for _, alias := range o.aliases {
+		if alias.Deprecated {
+			v.Deprecated = append(v.Deprecated, alias.Key)
+		} else {
+			v.Aliases = append(v.Aliases, alias.Key)
+		}
+	}
+	if !required && !o.sensitive {
+		v.Default = formatValue(fallback, o)
+	}
+	reg.Register(v)
+}
+
+// formatValue formats a value as it would be written in the environment, so that the lookup configured by o reads it
+// back: lists and maps are joined with the separators of o if the lookup splits them, and as JSON otherwise.
+func formatValue(value any, o *options) string {
+	return formatReflect(reflect.ValueOf(value), o, o.split)
+}
+
+// formatReflect formats v for formatValue. The elements of lists and maps are formatted with split unset, as they are
+// parsed like GetAs does.
+func formatReflect(v reflect.Value, o *options, split bool) string {
+	if !v.IsValid() {
+		return ""
+	}
+	switch v.Kind() {
+	case reflect.Slice, reflect.Map, reflect.Pointer, reflect.Interface:
+		if v.IsNil() {
+			return ""
+		}
+	}
+	if v.Type() == timeType {
+		t := v.Interface().(time.Time)
+		if t.IsZero() {
+			return ""
+		}
+		return t.Format(time.RFC3339Nano)
+	}
+	if s, ok := formatText(v); ok {
+		return s
+	}
+
+	switch {
+	case v.Kind() == reflect.Bool && (o.trueWords != nil || o.falseWords != nil):
+		words := o.falseWords
+		if v.Bool() {
+			words = o.trueWords
+		}
+		if len(words) > 0 {
+			return words[0]
+		}
+	case split && v.Kind() == reflect.Slice && v.Type().Elem().Kind() != reflect.Uint8:
+		elements := make([]string, v.Len())
+		for i := range elements {
+			elements[i] = formatReflect(v.Index(i), o, false)
+		}
+		return strings.Join(elements, o.listSeparator())
+	case split && v.Kind() == reflect.Map:
+		pairs := make([]string, 0, v.Len())
+		iter := v.MapRange()
+		for iter.Next() {
+			pairs = append(pairs, formatReflect(iter.Key(), o, false)+o.pairSeparator()+formatReflect(iter.Value(), o, false))
+		}
+		sort.Strings(pairs)
+		return strings.Join(pairs, o.listSeparator())
+	}
+
+	switch v.Kind() {
+	case reflect.Slice, reflect.Map, reflect.Array, reflect.Struct, reflect.Pointer:
+		b, err := json.Marshal(v.Interface())
+		if err != nil {
+			return ""
+		}
+		return string(b)
+	default:
+		return fmt.Sprint(v.Interface())
+	}
+}
+
+// formatText formats v with its MarshalText or String method, which are looked up through a pointer, so that those
+// of types like url.URL and regexp.Regexp are found.
+func formatText(v reflect.Value) (string, bool) {
+	ptr := v
+	if v.Kind() != reflect.Pointer {
+		ptr = reflect.New(v.Type())
+		ptr.Elem().Set(v)
+	}
+	switch m := ptr.Interface().(type) {
+	case encoding.TextMarshaler:
+		b, err := m.MarshalText()
+		return string(b), err == nil
+	case fmt.Stringer:
+		return m.String(), true
+	default:
+		return "", false
+	}
+}
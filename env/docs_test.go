@@ -0,0 +1,298 @@
+package env
+
+/*
+Copyright 2023 UMH Systems GmbH
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+import (
+	"bytes"
+	"encoding/json"
+	"math"
+	"net"
+	"net/netip"
+	"net/url"
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+)
+
+type docsTestConfig struct {
+	Brokers  string        `env:"DOCS_BROKERS,required" desc:"Kafka brokers"`
+	Password string        `env:"DOCS_PASSWORD,sensitive" default:"hunter2" desc:"Database password"`
+	Port     int           `env:"DOCS_PORT" default:"8080" desc:"HTTP port"`
+	Timeout  time.Duration `env:"DOCS_TIMEOUT" default:"30s"`
+	Topics   []string      `env:"DOCS_TOPICS" default:"a,b"`
+	Debug    bool          `env:"DOCS_DEBUG"`
+}
+
+// TestRegistry tests that lookups and struct tags register their variables
+func TestRegistry(t *testing.T) {
+	t.Parallel()
+
+	reg := NewRegistry()
+	if err := reg.RegisterStruct(&docsTestConfig{Debug: true}); err != nil {
+		t.Fatalf("RegisterStruct() error = %v", err)
+	}
+	r := NewReader(MapSource{"DOCS_RATIO": "0.5"}, WithRegistry(reg))
+	if _, err := r.GetAsFloat64("DOCS_RATIO", false, 1, Description("Sampling ratio")); err != nil {
+		t.Fatalf("GetAsFloat64() error = %v", err)
+	}
+	// A lookup without description keeps the registered one
+	if _, err := r.GetAsInt("DOCS_PORT", false, 9090); err != nil {
+		t.Fatalf("GetAsInt() error = %v", err)
+	}
+
+	want := []Var{
+		{Key: "DOCS_BROKERS", Type: "string", Description: "Kafka brokers", Required: true},
+		{Key: "DOCS_DEBUG", Type: "bool", Default: "true"},
+		{Key: "DOCS_PASSWORD", Type: "string", Description: "Database password", Sensitive: true},
+		{Key: "DOCS_PORT", Type: "int", Default: "9090", Description: "HTTP port"},
+		{Key: "DOCS_RATIO", Type: "float64", Default: "1", Description: "Sampling ratio"},
+		{Key: "DOCS_TIMEOUT", Type: "time.Duration", Default: "30s"},
+		{Key: "DOCS_TOPICS", Type: "[]string", Default: "a,b"},
+	}
+	if got := reg.Vars(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Vars() = %+v, want %+v", got, want)
+	}
+
+	if err := reg.RegisterStruct(docsTestConfig{}); err == nil {
+		t.Error("RegisterStruct() expected error for a non-pointer")
+	}
+}
+
+// TestRegistryMerge tests that registering a variable again merges it with the registered one
+func TestRegistryMerge(t *testing.T) {
+	t.Parallel()
+
+	reg := NewRegistry()
+	reg.Register(Var{Key: "MERGE_BROKER", Type: "string", Description: "MQTT broker", Sensitive: true, Aliases: []string{"MERGE_URL"}, Deprecated: []string{"MERGE_OLD"}})
+	reg.Register(Var{Key: "MERGE_BROKER", Type: "string", Required: true, Aliases: []string{"MERGE_HOST", "MERGE_URL"}})
+
+	want := []Var{{
+		Key:         "MERGE_BROKER",
+		Type:        "string",
+		Description: "MQTT broker",
+		Required:    true,
+		Sensitive:   true,
+		Aliases:     []string{"MERGE_URL", "MERGE_HOST"},
+		Deprecated:  []string{"MERGE_OLD"},
+	}}
+	if got := reg.Vars(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Vars() = %+v, want %+v", got, want)
+	}
+}
+
+// docsRoundTrip returns a test that registers value as the default of get, renders the registry with WriteDotenv and
+// reads the sample file back with get.
+func docsRoundTrip[T any](get func(*Reader, string, bool, T, ...Option) (T, error), value T, opts ...Option) func(t *testing.T) {
+	return func(t *testing.T) {
+		t.Parallel()
+		reg := NewRegistry()
+		opts = append(opts[:len(opts):len(opts)], WithRegistry(reg))
+		if _, err := get(NewReader(MapSource{}), "ROUND_TRIP", false, value, opts...); err != nil {
+			t.Fatalf("lookup error = %v", err)
+		}
+		var buf bytes.Buffer
+		if err := reg.WriteDotenv(&buf); err != nil {
+			t.Fatal(err)
+		}
+		sample := buf.String()
+		values, err := ParseDotenv(&buf)
+		if err != nil {
+			t.Fatalf("ParseDotenv() error = %v for\n%s", err, sample)
+		}
+
+		var zero T
+		got, err := get(NewReader(MapSource(values)), "ROUND_TRIP", true, zero, opts...)
+		if err != nil {
+			t.Fatalf("reading back\n%s\nerror = %v", sample, err)
+		}
+		if !reflect.DeepEqual(got, value) {
+			t.Errorf("reading back\n%s\n= %v, want %v", sample, got, value)
+		}
+	}
+}
+
+// TestRegistryRoundTrip tests that the documented defaults of every built-in type are read back as they were
+func TestRegistryRoundTrip(t *testing.T) {
+	t.Parallel()
+
+	broker, err := url.Parse("mqtt://user@broker:1883/umh?qos=1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, network, err := net.ParseCIDR("192.168.0.0/16")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		run  func(t *testing.T)
+		name string
+	}{
+		{name: "Case 1: string", run: docsRoundTrip(GetAsFrom[string], "say \"hi\" # $HOME")},
+		{name: "Case 2: bool", run: docsRoundTrip(GetAsFrom[bool], true)},
+		{name: "Case 3: bool with its own words", run: docsRoundTrip(GetAsFrom[bool], true, BoolWords([]string{"ja"}, []string{"nein"}), StrictBool())},
+		{name: "Case 4: int", run: docsRoundTrip(GetAsFrom[int], -42)},
+		{name: "Case 5: int8", run: docsRoundTrip(GetAsFrom[int8], int8(-128))},
+		{name: "Case 6: int16", run: docsRoundTrip(GetAsFrom[int16], int16(32767))},
+		{name: "Case 7: int32", run: docsRoundTrip(GetAsFrom[int32], int32(-7))},
+		{name: "Case 8: int64", run: docsRoundTrip(GetAsFrom[int64], int64(math.MinInt64))},
+		{name: "Case 9: uint", run: docsRoundTrip(GetAsFrom[uint], uint(7))},
+		{name: "Case 10: uint8", run: docsRoundTrip(GetAsFrom[uint8], uint8(255))},
+		{name: "Case 11: uint16", run: docsRoundTrip(GetAsFrom[uint16], uint16(1883))},
+		{name: "Case 12: uint32", run: docsRoundTrip(GetAsFrom[uint32], uint32(math.MaxUint32))},
+		{name: "Case 13: uint64", run: docsRoundTrip(GetAsFrom[uint64], uint64(math.MaxUint64))},
+		{name: "Case 14: float32", run: docsRoundTrip(GetAsFrom[float32], float32(0.1))},
+		{name: "Case 15: float64", run: docsRoundTrip(GetAsFrom[float64], 1e-9)},
+		{name: "Case 16: time.Duration", run: docsRoundTrip(GetAsFrom[time.Duration], 90*time.Second+time.Millisecond)},
+		{name: "Case 17: time.Time", run: docsRoundTrip(GetAsFrom[time.Time], time.Date(2023, 6, 1, 12, 30, 0, 5, time.UTC))},
+		{name: "Case 18: url.URL", run: docsRoundTrip(GetAsFrom[url.URL], *broker)},
+		{name: "Case 19: net.IP", run: docsRoundTrip(GetAsFrom[net.IP], net.ParseIP("fe80::1"))},
+		{name: "Case 20: net.IPNet", run: docsRoundTrip(GetAsFrom[net.IPNet], *network)},
+		{name: "Case 21: netip.AddrPort", run: docsRoundTrip(GetAsFrom[netip.AddrPort], netip.MustParseAddrPort("[::1]:8080"))},
+		{name: "Case 22: regexp.Regexp", run: docsRoundTrip(GetAsFrom[regexp.Regexp], *regexp.MustCompile(`^umh\.v1\.[a-z]+$`))},
+		{name: "Case 23: List", run: docsRoundTrip(GetAsSliceFrom[string], []string{"a:1", "b:2"})},
+		{name: "Case 24: List with a separator", run: docsRoundTrip(GetAsSliceFrom[int], []int{1, 2}, Separator(";"))},
+		{name: "Case 25: Map", run: docsRoundTrip(GetAsMapFrom[string, int], map[string]int{"a": 1, "b": 2}, Separator(";"), KeyValueSeparator(":"))},
+		{name: "Case 26: List read as JSON", run: docsRoundTrip(GetAsFrom[[]string], []string{"a:1", "b,2"})},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, tt.run)
+	}
+}
+
+// TestRegistryWrite tests the documentation formats
+func TestRegistryWrite(t *testing.T) {
+	t.Parallel()
+
+	reg := NewRegistry()
+	reg.Register(Var{Key: "PORT", Type: "int", Default: "8080", Description: "HTTP port"})
+	reg.Register(Var{Key: "PASSWORD", Type: "string", Default: "hunter2", Required: true, Sensitive: true})
+	reg.Register(Var{Key: "GREETING", Type: "string", Default: "hello world", Description: "a | b"})
+
+	tests := []struct {
+		name   string
+		format DocFormat
+		want   string
+	}{
+		{
+			name:   "Case 1: Markdown",
+			format: DocMarkdown,
+			want: "| Variable | Type | Default | Required | Description |\n" +
+				"|----------|------|---------|----------|-------------|\n" +
+				"| `GREETING` | string | `hello world` | no | a \\| b |\n" +
+				"| `PASSWORD` | string |  | yes |  |\n" +
+				"| `PORT` | int | `8080` | no | HTTP port |\n",
+		},
+		{
+			name:   "Case 2: Dotenv",
+			format: DocDotenv,
+			want: "# a | b (string)\nGREETING=\"hello world\"\n\n" +
+				"# (string, required, sensitive)\nPASSWORD=\n\n" +
+				"# HTTP port (int)\nPORT=8080\n",
+		},
+		{
+			name:   "Case 3: Helm",
+			format: DocHelm,
+			want: "env:\n" +
+				"  # a | b (string)\n  GREETING: \"hello world\"\n" +
+				"  # (string, required, sensitive)\n  PASSWORD: \"\"\n" +
+				"  # HTTP port (int)\n  PORT: \"8080\"\n",
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			var buf bytes.Buffer
+			if err := reg.Write(&buf, tt.format); err != nil {
+				t.Fatalf("Write() error = %v", err)
+			}
+			if got := buf.String(); got != tt.want {
+				t.Errorf("Write() =\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+
+	var buf bytes.Buffer
+	if err := reg.Write(&buf, "xml"); err == nil {
+		t.Error("Write() expected error for an unknown format")
+	}
+}
+
+// TestRegistryJSONSchema tests the JSON Schema output
+func TestRegistryJSONSchema(t *testing.T) {
+	t.Parallel()
+
+	reg := NewRegistry()
+	reg.Register(Var{Key: "PORT", Type: "int", Default: "8080", Description: "HTTP port"})
+	reg.Register(Var{Key: "PASSWORD", Type: "string", Default: "hunter2", Required: true, Sensitive: true})
+	reg.Register(Var{Key: "DEBUG", Type: "bool", Default: "true"})
+
+	var buf bytes.Buffer
+	if err := reg.WriteJSONSchema(&buf); err != nil {
+		t.Fatalf("WriteJSONSchema() error = %v", err)
+	}
+	var schema struct {
+		Properties map[string]map[string]any `json:"properties"`
+		Type       string                    `json:"type"`
+		Required   []string                  `json:"required"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &schema); err != nil {
+		t.Fatalf("invalid JSON: %v", err)
+	}
+	if schema.Type != "object" || !reflect.DeepEqual(schema.Required, []string{"PASSWORD"}) {
+		t.Errorf("schema = %+v", schema)
+	}
+	want := map[string]map[string]any{
+		"DEBUG":    {"type": "boolean", "default": true},
+		"PASSWORD": {"type": "string", "writeOnly": true},
+		"PORT":     {"type": "integer", "default": float64(8080), "description": "HTTP port"},
+	}
+	if !reflect.DeepEqual(schema.Properties, want) {
+		t.Errorf("properties = %v, want %v", schema.Properties, want)
+	}
+}
+
+// TestReadRegistry tests that the JSON output can be read back
+func TestReadRegistry(t *testing.T) {
+	t.Parallel()
+
+	reg := NewRegistry()
+	reg.Register(Var{Key: "PORT", Type: "int", Default: "8080", Description: "HTTP port"})
+	reg.Register(Var{Key: "BROKERS", Type: "string", Required: true})
+
+	var buf bytes.Buffer
+	if err := reg.Write(&buf, DocJSON); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	read, err := ReadRegistry(&buf)
+	if err != nil {
+		t.Fatalf("ReadRegistry() error = %v", err)
+	}
+	if !reflect.DeepEqual(read.Vars(), reg.Vars()) {
+		t.Errorf("ReadRegistry() = %+v, want %+v", read.Vars(), reg.Vars())
+	}
+
+	if _, err = ReadRegistry(strings.NewReader("{")); err == nil {
+		t.Error("ReadRegistry() expected error for invalid JSON")
+	}
+}
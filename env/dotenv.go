@@ -0,0 +1,432 @@
+package env
+
+/*
+Copyright 2023 UMH Systems GmbH
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// defaultDotenvPath is loaded if no path is given.
+const defaultDotenvPath = ".env"
+
+// LoadDotenv reads the given .env files, or ".env" if no path is given, and sets their variables in the environment
+// of the process, so they are visible to all GetAs* functions. Variables that are already set are kept, which lets
+// the real environment override the file. If several files define a variable, the last one wins.
+//
+// The files use the common dotenv dialect:
+//
+//	# comments, on their own line or after an unquoted value
+//	export KAFKA_BROKERS=localhost:9092
+//	SINGLE='taken literally, may span lines'
+//	DOUBLE="supports \n, \t, \" and \\ escapes"
+//	TOPIC=${PREFIX}.events
+//	LEVEL=${LOG_LEVEL:-INFO}
+//
+// ${VAR} and $VAR are replaced by the value of VAR, ${VAR:-default} falls back to default if VAR is unset or empty
+// and ${VAR-default} only if VAR is unset. Interpolation happens in unquoted and double-quoted values.
+func LoadDotenv(paths ...string) error {
+	return loadDotenv(paths, false)
+}
+
+// OverloadDotenv is LoadDotenv, but overrides variables that are already set.
+func OverloadDotenv(paths ...string) error {
+	return loadDotenv(paths, true)
+}
+
+// ReadDotenv parses the given .env files, or ".env" if no path is given, and returns their variables without
+// changing the environment. If several files define a variable, the last one wins.
+func ReadDotenv(paths ...string) (map[string]string, error) {
+	values, _, err := readDotenv(paths, true)
+	return values, err
+}
+
+// ParseDotenv parses a single .env document. Interpolated variables are looked up in the document first and in
+// the environment of the process second.
+func ParseDotenv(r io.Reader) (map[string]string, error) {
+	values := make(map[string]string)
+	set := func(key, value string) {
+		values[key] = value
+	}
+	if err := parseDotenv(r, "", dotenvLookup(values, true), set); err != nil {
+		return nil, err
+	}
+	return values, nil
+}
+
+// loadDotenv reads paths and sets the variables in the environment of the process. The file each variable came
+// from is remembered, so OSSource can report it as its origin.
+func loadDotenv(paths []string, override bool) error {
+	values, files, err := readDotenv(paths, override)
+	if err != nil {
+		return err
+	}
+	for key, value := range values {
+		if _, set := os.LookupEnv(key); set && !override {
+			continue
+		}
+		if err = os.Setenv(key, value); err != nil {
+			return fmt.Errorf("failed to set environment variable %s: %w", key, err)
+		}
+		dotenvOrigins.Store(key, dotenvOrigin{path: files[key], value: value})
+	}
+	return nil
+}
+
+// readDotenv parses paths in order and returns the values and the file that defined each of them. override decides
+// whether values from the files or from the process take precedence during interpolation, matching the way the
+// values are applied afterwards.
+func readDotenv(paths []string, override bool) (values, files map[string]string, err error) {
+	if len(paths) == 0 {
+		paths = []string{defaultDotenvPath}
+	}
+
+	values = make(map[string]string)
+	files = make(map[string]string)
+	lookup := dotenvLookup(values, override)
+	for _, path := range paths {
+		path := path
+		set := func(key, value string) {
+			values[key] = value
+			files[key] = path
+		}
+		if err = parseDotenvFile(path, lookup, set); err != nil {
+			return nil, nil, err
+		}
+	}
+	return values, files, nil
+}
+
+// parseDotenvFile parses the file at path.
+func parseDotenvFile(path string, lookup func(string) (string, bool), set func(key, value string)) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return parseDotenv(f, path, lookup, set)
+}
+
+// dotenvLookup resolves interpolated variables from values and the environment of the process.
+func dotenvLookup(values map[string]string, override bool) func(string) (string, bool) {
+	return func(key string) (string, bool) {
+		if !override {
+			if value, ok := os.LookupEnv(key); ok {
+				return value, true
+			}
+		}
+		if value, ok := values[key]; ok {
+			return value, true
+		}
+		return os.LookupEnv(key)
+	}
+}
+
+// dotenvParser is a scanner for the dotenv dialect described at LoadDotenv.
+type dotenvParser struct {
+	lookup func(string) (string, bool)
+	path   string
+	src    string
+	pos    int
+	line   int
+}
+
+// parseDotenv parses r and calls set for every assignment. path is only used in error messages.
+func parseDotenv(r io.Reader, path string, lookup func(string) (string, bool), set func(key, value string)) error {
+	content, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	p := &dotenvParser{src: string(content), path: path, line: 1, lookup: lookup}
+	for {
+		key, value, ok, err := p.next()
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return nil
+		}
+		set(key, value)
+	}
+}
+
+// next returns the next assignment, or ok == false at the end of the input.
+func (p *dotenvParser) next() (key, value string, ok bool, err error) {
+	for {
+		p.skip(" \t\r\n")
+		if p.eof() {
+			return "", "", false, nil
+		}
+		if p.peek() != '#' {
+			break
+		}
+		p.skipLine()
+	}
+
+	key = p.identifier()
+	if key == "export" && !p.eof() && (p.peek() == ' ' || p.peek() == '\t') {
+		p.skip(" \t")
+		key = p.identifier()
+	}
+	if key == "" {
+		return "", "", false, p.errorf("expected a variable name")
+	}
+
+	p.skip(" \t")
+	if p.eof() || p.peek() != '=' {
+		return "", "", false, p.errorf("expected '=' after %s", key)
+	}
+	p.pos++
+	p.skip(" \t")
+
+	switch {
+	case p.eof():
+		value = ""
+	case p.peek() == '\'':
+		value, err = p.singleQuoted()
+	case p.peek() == '"':
+		value, err = p.doubleQuoted()
+	default:
+		value, err = p.unquoted()
+	}
+	if err != nil {
+		return "", "", false, err
+	}
+	return key, value, true, nil
+}
+
+// identifier reads a variable name.
+func (p *dotenvParser) identifier() string {
+	start := p.pos
+	for !p.eof() {
+		c := p.peek()
+		if c == '_' || c == '.' || isAlpha(c) || (p.pos > start && isDigit(c)) {
+			p.pos++
+			continue
+		}
+		break
+	}
+	return p.src[start:p.pos]
+}
+
+// singleQuoted reads a value in single quotes, which is taken literally.
+func (p *dotenvParser) singleQuoted() (string, error) {
+	startLine := p.line
+	p.pos++
+	end := strings.IndexByte(p.src[p.pos:], '\'')
+	if end < 0 {
+		p.line = startLine
+		return "", p.errorf("unterminated single-quoted value")
+	}
+	value := p.src[p.pos : p.pos+end]
+	p.line += strings.Count(value, "\n")
+	p.pos += end + 1
+	return value, p.endOfValue()
+}
+
+// doubleQuoted reads a value in double quotes, which supports escape sequences and interpolation.
+func (p *dotenvParser) doubleQuoted() (string, error) {
+	startLine := p.line
+	p.pos++
+	start := p.pos
+	for ; ; p.pos++ {
+		if p.eof() {
+			p.line = startLine
+			return "", p.errorf("unterminated double-quoted value")
+		}
+		c := p.peek()
+		if c == '\\' {
+			p.pos++
+			continue
+		}
+		if c == '"' {
+			break
+		}
+	}
+	raw := p.src[start:p.pos]
+	p.line += strings.Count(raw, "\n")
+	p.pos++
+
+	value, err := expand(raw, true, p.lookup)
+	if err != nil {
+		return "", p.errorf("%v", err)
+	}
+	return value, p.endOfValue()
+}
+
+// unquoted reads the rest of the line, without trailing whitespace and comments.
+func (p *dotenvParser) unquoted() (string, error) {
+	start := p.pos
+	for !p.eof() && p.peek() != '\n' {
+		if p.peek() == '#' && p.pos > start && (p.src[p.pos-1] == ' ' || p.src[p.pos-1] == '\t') {
+			break
+		}
+		p.pos++
+	}
+	raw := strings.TrimRight(p.src[start:p.pos], " \t\r")
+	p.skipLine()
+
+	value, err := expand(raw, false, p.lookup)
+	if err != nil {
+		return "", p.errorf("%v", err)
+	}
+	return value, nil
+}
+
+// endOfValue makes sure nothing but whitespace or a comment follows a quoted value.
+func (p *dotenvParser) endOfValue() error {
+	p.skip(" \t\r")
+	if p.eof() || p.peek() == '\n' || p.peek() == '#' {
+		p.skipLine()
+		return nil
+	}
+	return p.errorf("unexpected %q after quoted value", p.peek())
+}
+
+func (p *dotenvParser) eof() bool {
+	return p.pos >= len(p.src)
+}
+
+func (p *dotenvParser) peek() byte {
+	return p.src[p.pos]
+}
+
+// skip advances past any of the bytes in set.
+func (p *dotenvParser) skip(set string) {
+	for !p.eof() && strings.IndexByte(set, p.peek()) >= 0 {
+		if p.peek() == '\n' {
+			p.line++
+		}
+		p.pos++
+	}
+}
+
+// skipLine advances past the next line break.
+func (p *dotenvParser) skipLine() {
+	for !p.eof() {
+		c := p.peek()
+		p.pos++
+		if c == '\n' {
+			p.line++
+			return
+		}
+	}
+}
+
+func (p *dotenvParser) errorf(format string, args ...any) error {
+	path := p.path
+	if path == "" {
+		path = "dotenv"
+	}
+	return fmt.Errorf("%s:%d: %s", path, p.line, fmt.Sprintf(format, args...))
+}
+
+// expand replaces ${VAR}, ${VAR:-default}, ${VAR-default} and $VAR in s. If escapes is true, backslash escape
+// sequences are processed as well, and \$ produces a literal dollar sign.
+func expand(s string, escapes bool, lookup func(string) (string, bool)) (string, error) {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case escapes && c == '\\' && i+1 < len(s):
+			i++
+			switch s[i] {
+			case 'n':
+				b.WriteByte('\n')
+			case 'r':
+				b.WriteByte('\r')
+			case 't':
+				b.WriteByte('\t')
+			case '"', '\\', '$':
+				b.WriteByte(s[i])
+			default:
+				b.WriteByte('\\')
+				b.WriteByte(s[i])
+			}
+		case c == '$' && i+1 < len(s) && s[i+1] == '{':
+			end := closingBrace(s, i+2)
+			if end < 0 {
+				return "", errors.New("unterminated ${ in value")
+			}
+			value, err := expandBraced(s[i+2:end], escapes, lookup)
+			if err != nil {
+				return "", err
+			}
+			b.WriteString(value)
+			i = end
+		case c == '$' && i+1 < len(s) && (isAlpha(s[i+1]) || s[i+1] == '_'):
+			j := i + 1
+			for j < len(s) && (isAlpha(s[j]) || isDigit(s[j]) || s[j] == '_') {
+				j++
+			}
+			value, _ := lookup(s[i+1 : j])
+			b.WriteString(value)
+			i = j - 1
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return b.String(), nil
+}
+
+// expandBraced resolves the content of ${...}.
+func expandBraced(expr string, escapes bool, lookup func(string) (string, bool)) (string, error) {
+	name, def, op := expr, "", ""
+	if i := strings.Index(expr, ":-"); i >= 0 {
+		name, def, op = expr[:i], expr[i+2:], ":-"
+	} else if i = strings.IndexByte(expr, '-'); i >= 0 {
+		name, def, op = expr[:i], expr[i+1:], "-"
+	}
+	if name == "" {
+		return "", fmt.Errorf("empty variable name in ${%s}", expr)
+	}
+
+	value, set := lookup(name)
+	if (op == ":-" && value == "") || (op == "-" && !set) {
+		return expand(def, escapes, lookup)
+	}
+	return value, nil
+}
+
+// closingBrace returns the index of the brace closing a ${ that starts before from, allowing nested ${...} in
+// default values.
+func closingBrace(s string, from int) int {
+	depth := 1
+	for i := from; i < len(s); i++ {
+		switch s[i] {
+		case '{':
+			depth++
+		case '}':
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+func isAlpha(c byte) bool {
+	return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
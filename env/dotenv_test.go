@@ -0,0 +1,160 @@
+package env
+
+/*
+Copyright 2023 UMH Systems GmbH
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// TestParseDotenv tests the ParseDotenv function
+func TestParseDotenv(t *testing.T) {
+	t.Setenv("DOTENV_OS_VAR", "from-os")
+
+	tests := []struct {
+		want    map[string]string
+		name    string
+		input   string
+		wantErr bool
+	}{
+		{
+			name:  "Case 1: Plain values, comments and blank lines",
+			input: "# comment\n\nA=1\nB = two words  # trailing comment\nC=a#b\n",
+			want:  map[string]string{"A": "1", "B": "two words", "C": "a#b"},
+		},
+		{
+			name:  "Case 2: Export prefix",
+			input: "export A=1\nexport\tB=2\n",
+			want:  map[string]string{"A": "1", "B": "2"},
+		},
+		{
+			name:  "Case 3: Single quotes are literal",
+			input: `A='${B} \n # not a comment'`,
+			want:  map[string]string{"A": `${B} \n # not a comment`},
+		},
+		{
+			name:  "Case 4: Double quotes process escapes",
+			input: `A="line1\nline2\t\"quoted\" \\ \$B"`,
+			want:  map[string]string{"A": "line1\nline2\t\"quoted\" \\ $B"},
+		},
+		{
+			name:  "Case 5: Multi-line values",
+			input: "A=\"first\nsecond\"\nB='third\nfourth'\nC=last\n",
+			want:  map[string]string{"A": "first\nsecond", "B": "third\nfourth", "C": "last"},
+		},
+		{
+			name:  "Case 6: Interpolation from the document and the environment",
+			input: "A=umh\nB=${A}.v1\nC=\"$A-$DOTENV_OS_VAR\"\nD='$A'\n",
+			want:  map[string]string{"A": "umh", "B": "umh.v1", "C": "umh-from-os", "D": "$A"},
+		},
+		{
+			name:  "Case 7: Interpolation defaults",
+			input: "EMPTY=\nA=${EMPTY:-x}\nB=${EMPTY-x}\nC=${DOTENV_NONEXISTENT_VAR-y}\nD=${DOTENV_NONEXISTENT_VAR:-${EMPTY:-z}}\n",
+			want:  map[string]string{"EMPTY": "", "A": "x", "B": "", "C": "y", "D": "z"},
+		},
+		{
+			name:    "Case 8: Missing equals sign, return error",
+			input:   "A=1\nB\n",
+			wantErr: true,
+		},
+		{
+			name:    "Case 9: Unterminated quote, return error",
+			input:   `A="unterminated`,
+			wantErr: true,
+		},
+		{
+			name:    "Case 10: Garbage after quoted value, return error",
+			input:   `A="value" garbage`,
+			wantErr: true,
+		},
+		{
+			name:    "Case 11: Unterminated interpolation, return error",
+			input:   `A=${B`,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseDotenv(strings.NewReader(tt.input))
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ParseDotenv() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseDotenv() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestParseDotenvErrorLine tests that syntax errors name the line
+func TestParseDotenvErrorLine(t *testing.T) {
+	_, err := ParseDotenv(strings.NewReader("A=1\n\n# comment\nB\n"))
+	if err == nil || !strings.Contains(err.Error(), ":4:") {
+		t.Errorf("ParseDotenv() error = %v, want it to name line 4", err)
+	}
+}
+
+// TestLoadDotenv tests that LoadDotenv keeps and OverloadDotenv replaces existing variables
+func TestLoadDotenv(t *testing.T) {
+	dir := t.TempDir()
+	first := filepath.Join(dir, ".env")
+	second := filepath.Join(dir, ".env.local")
+	if err := os.WriteFile(first, []byte("DOTENV_NEW=first\nDOTENV_EXISTING=file\nDOTENV_PORT=8080\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(second, []byte("DOTENV_NEW=second\nDOTENV_COPY=${DOTENV_EXISTING}\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Setenv("DOTENV_EXISTING", "process")
+	t.Setenv("DOTENV_NEW", "")
+	t.Setenv("DOTENV_COPY", "")
+	t.Setenv("DOTENV_PORT", "")
+	for _, key := range []string{"DOTENV_NEW", "DOTENV_COPY", "DOTENV_PORT"} {
+		if err := os.Unsetenv(key); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := LoadDotenv(first, second); err != nil {
+		t.Fatalf("LoadDotenv() error = %v", err)
+	}
+	for key, want := range map[string]string{"DOTENV_NEW": "second", "DOTENV_EXISTING": "process", "DOTENV_COPY": "process"} {
+		if got := os.Getenv(key); got != want {
+			t.Errorf("after LoadDotenv() %s = %q, want %q", key, got, want)
+		}
+	}
+	if got, err := GetAsInt("DOTENV_PORT", true, 0); err != nil || got != 8080 {
+		t.Errorf("GetAsInt() = %d, %v, want value from .env", got, err)
+	}
+
+	if err := OverloadDotenv(first); err != nil {
+		t.Fatalf("OverloadDotenv() error = %v", err)
+	}
+	if got := os.Getenv("DOTENV_EXISTING"); got != "file" {
+		t.Errorf("after OverloadDotenv() DOTENV_EXISTING = %q, want %q", got, "file")
+	}
+
+	if err := LoadDotenv(filepath.Join(dir, "missing")); err == nil {
+		t.Error("LoadDotenv() expected error for a missing file")
+	}
+}
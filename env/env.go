@@ -17,115 +17,93 @@ limitations under the License.
 */
 
 import (
-	"encoding/json"
-	"fmt"
-	"os"
-	"strconv"
+	"reflect"
+	"time"
 )
 
 // GetAsString returns the value of the environment variable as a string. If the environment variable is not set and not required, the fallback value is returned.
-func GetAsString(key string, required bool, fallback string) (string, error) {
-	value, set := os.LookupEnv(key)
-
-	// Check if the environment variable is set
-	if !set {
-		// If not required, return the fallback value
-		if !required {
-			return fallback, nil
-		}
-		// If required, return an error
-		return "", fmt.Errorf("environment variable %s is required but not set", key)
-	}
+func GetAsString(key string, required bool, fallback string, opts ...Option) (string, error) {
+	return defaultReader.GetAsString(key, required, fallback, opts...)
+}
 
-	return value, nil
+// GetAsInt returns the value of the environment variable as an int. Besides decimal numbers, the prefixes 0x, 0o and 0b and underscores between digits are accepted, as in "0x9C41" or "1_000". If the value does not fit, the returned *ParseError wraps a *RangeError holding the allowed bounds. If the environment variable is not set and not required, the fallback value is returned.
+func GetAsInt(key string, required bool, fallback int, opts ...Option) (int, error) {
+	return defaultReader.GetAsInt(key, required, fallback, opts...)
 }
 
-// GetAsInt returns the value of the environment variable as an int. If the environment variable is not set and not required, the fallback value is returned.
-func GetAsInt(key string, required bool, fallback int) (int, error) {
-	value, set := os.LookupEnv(key)
+// GetAsInt8 returns the value of the environment variable as an int8, following the rules of GetAsInt.
+func GetAsInt8(key string, required bool, fallback int8, opts ...Option) (int8, error) {
+	return defaultReader.GetAsInt8(key, required, fallback, opts...)
+}
 
-	// Check if the environment variable is set
-	if !set {
-		// If not required, return the fallback value
-		if !required {
-			return fallback, nil
-		}
-		// If required, return an error
-		return 0, fmt.Errorf("environment variable %s is required but not set", key)
-	}
+// GetAsInt16 returns the value of the environment variable as an int16, following the rules of GetAsInt.
+func GetAsInt16(key string, required bool, fallback int16, opts ...Option) (int16, error) {
+	return defaultReader.GetAsInt16(key, required, fallback, opts...)
+}
 
-	i, err := strconv.Atoi(value)
-	if err != nil {
-		return fallback, fmt.Errorf("environment variable %s is not an integer. using fallback value", key)
-	}
+// GetAsInt32 returns the value of the environment variable as an int32, following the rules of GetAsInt.
+func GetAsInt32(key string, required bool, fallback int32, opts ...Option) (int32, error) {
+	return defaultReader.GetAsInt32(key, required, fallback, opts...)
+}
 
-	return i, nil
+// GetAsInt64 returns the value of the environment variable as an int64, following the rules of GetAsInt.
+func GetAsInt64(key string, required bool, fallback int64, opts ...Option) (int64, error) {
+	return defaultReader.GetAsInt64(key, required, fallback, opts...)
 }
 
-// GetAsUint64 returns the value of the environment variable as an uint64. If the environment variable is not set and not required, the fallback value is returned.
-func GetAsUint64(key string, required bool, fallback uint64) (uint64, error) {
-	value, set := os.LookupEnv(key)
+// GetAsUint returns the value of the environment variable as a uint, following the rules of GetAsInt. Negative values are out of range.
+func GetAsUint(key string, required bool, fallback uint, opts ...Option) (uint, error) {
+	return defaultReader.GetAsUint(key, required, fallback, opts...)
+}
 
-	// Check if the environment variable is set
-	if !set {
-		// If not required, return the fallback value
-		if !required {
-			return fallback, nil
-		}
-		// If required, return an error
-		return 0, fmt.Errorf("environment variable %s is required but not set", key)
-	}
+// GetAsUint8 returns the value of the environment variable as a uint8, following the rules of GetAsInt. Negative values are out of range.
+func GetAsUint8(key string, required bool, fallback uint8, opts ...Option) (uint8, error) {
+	return defaultReader.GetAsUint8(key, required, fallback, opts...)
+}
 
-	i, err := strconv.ParseUint(value, 10, 64)
-	if err != nil {
-		return fallback, fmt.Errorf("environment variable %s is not an integer. using fallback value", key)
-	}
+// GetAsUint16 returns the value of the environment variable as a uint16, following the rules of GetAsInt. Negative values are out of range.
+func GetAsUint16(key string, required bool, fallback uint16, opts ...Option) (uint16, error) {
+	return defaultReader.GetAsUint16(key, required, fallback, opts...)
+}
 
-	return i, nil
+// GetAsUint32 returns the value of the environment variable as a uint32, following the rules of GetAsInt. Negative values are out of range.
+func GetAsUint32(key string, required bool, fallback uint32, opts ...Option) (uint32, error) {
+	return defaultReader.GetAsUint32(key, required, fallback, opts...)
 }
 
-// GetAsFloat64 returns the value of the environment variable as a float64. If the environment variable is not set and not required, the fallback value is returned.
-func GetAsFloat64(key string, required bool, fallback float64) (float64, error) {
-	value, set := os.LookupEnv(key)
-
-	// Check if the environment variable is set
-	if !set {
-		// If not required, return the fallback value
-		if !required {
-			return fallback, nil
-		}
-		// If required, return an error
-		return 0, fmt.Errorf("environment variable %s is required but not set", key)
-	}
+// GetAsUint64 returns the value of the environment variable as a uint64, following the rules of GetAsInt. Negative values are out of range. If the environment variable is not set and not required, the fallback value is returned.
+func GetAsUint64(key string, required bool, fallback uint64, opts ...Option) (uint64, error) {
+	return defaultReader.GetAsUint64(key, required, fallback, opts...)
+}
 
-	f, err := strconv.ParseFloat(value, 64)
-	if err != nil {
-		return fallback, fmt.Errorf("environment variable %s is not a float. using fallback value", key)
-	}
+// GetAsFloat64 returns the value of the environment variable as a float64. If the environment variable is not set and not required, the fallback value is returned.
+func GetAsFloat64(key string, required bool, fallback float64, opts ...Option) (float64, error) {
+	return defaultReader.GetAsFloat64(key, required, fallback, opts...)
+}
 
-	return f, nil
+// GetAsBool returns the value of the environment variable as a bool. The words true/false, yes/no, y/n, on/off, enabled/disabled and 1/0 are accepted in any case, as well as the other forms of strconv.ParseBool, such as "t"; see StrictBool and BoolWords. If the environment variable is not set and not required, the fallback value is returned.
+func GetAsBool(key string, required bool, fallback bool, opts ...Option) (bool, error) {
+	return defaultReader.GetAsBool(key, required, fallback, opts...)
 }
 
-// GetAsBool returns the value of the environment variable as a bool. If the environment variable is not set and not required, the fallback value is returned.
-func GetAsBool(key string, required bool, fallback bool) (bool, error) {
-	value, set := os.LookupEnv(key)
+// GetAsDuration returns the value of the environment variable as a time.Duration. The value may use Go duration syntax, such as "1m30s", or be a bare number in the unit set by the Unit option, which defaults to seconds. If the environment variable is not set and not required, the fallback value is returned.
+func GetAsDuration(key string, required bool, fallback time.Duration, opts ...Option) (time.Duration, error) {
+	return defaultReader.GetAsDuration(key, required, fallback, opts...)
+}
 
-	// Check if the environment variable is set
-	if !set {
-		// If not required, return the fallback value
-		if !required {
-			return fallback, nil
-		}
-		// If required, return an error
-		return false, fmt.Errorf("environment variable %s is required but not set", key)
-	}
+// GetAsTime returns the value of the environment variable as a time.Time. The value may be an RFC 3339 timestamp, Unix seconds or Unix milliseconds; integers of at least 10^11 are taken as milliseconds. If the environment variable is not set and not required, the fallback value is returned.
+func GetAsTime(key string, required bool, fallback time.Time, opts ...Option) (time.Time, error) {
+	return defaultReader.GetAsTime(key, required, fallback, opts...)
+}
 
-	b, err := strconv.ParseBool(value)
-	if err != nil {
-		return fallback, fmt.Errorf("environment variable %s is not a boolean. using fallback value", key)
-	}
+// GetAsQuantity returns the value of the environment variable as an int, parsed by parse.Quantity, so that sizes can be given as "10Mi" or "1.5G". If the value cannot be parsed, the returned *ParseError wraps the parse package error, such as parse.ErrSuffix or parse.ErrOverflow. If the environment variable is not set and not required, the fallback value is returned.
+func GetAsQuantity(key string, required bool, fallback int, opts ...Option) (int, error) {
+	return defaultReader.GetAsQuantity(key, required, fallback, opts...)
+}
 
-	return b, nil
+// GetAs returns the value of the environment variable as a T, parsed by the parser registered for T. There are built-in parsers for string, bool, all integer and float types, time.Duration, time.Time, url.URL, net.IP, net.IPNet, netip.AddrPort and regexp.Regexp, and RegisterParser adds more. Types implementing encoding.TextUnmarshaler are parsed with UnmarshalText, pointers like the type they point to, and all other types are unmarshaled from JSON. If the environment variable is not set and not required, the fallback value is returned.
+func GetAs[T any](key string, required bool, fallback T, opts ...Option) (T, error) {
+	return GetAsFrom(defaultReader, key, required, fallback, opts...)
 }
 
 // GetAsType retrieves the value of an environment variable by the given key,
@@ -145,29 +123,44 @@ func GetAsBool(key string, required bool, fallback bool) (bool, error) {
 //
 // Returns: an error if there is an error getting the environment variable value
 // or unmarshaling it to the target value, or nil if successful.
-func GetAsType[T any](key string, unmarshalTo *T, required bool, fallback T) error {
-	value, set := os.LookupEnv(key)
-
-	// Check if the value is null or empty
-	if !set {
-		// If not required, return the fallback value
-		if !required {
-			var ptr *T = &fallback
-			*unmarshalTo = *ptr
-			return nil
-		}
-		// If required, panic with an error message
-		return fmt.Errorf("environment variable %s is required but not set", key)
+//
+// With the Merge option, the value is unmarshaled onto a copy of fallback
+// instead, so it only overrides the fields it mentions. DisallowUnknownFields
+// rejects object fields that T does not have. Errors in a nested field wrap a
+// *JSONError holding its path.
+func GetAsType[T any](key string, unmarshalTo *T, required bool, fallback T, opts ...Option) error {
+	return GetAsTypeFrom(defaultReader, key, unmarshalTo, required, fallback, opts...)
+}
+
+// typeName returns the name of T as used in error messages.
+func typeName[T any]() string {
+	return reflect.TypeOf((*T)(nil)).Elem().String()
+}
+
+// The getters of Load work on reflect.Value, so that a single get handles every field. The helpers below look through
+// such a value to the one it holds.
+
+// typeNameOf returns the name of the type of v as used in error messages.
+func typeNameOf[T any](v T) string {
+	if rv, ok := any(v).(reflect.Value); ok {
+		return rv.Type().String()
 	}
+	return typeName[T]()
+}
 
-	// Unmarshal the environment variable value to the target value
-	err := json.Unmarshal([]byte(value), &unmarshalTo)
-	if err != nil {
-		// If unmarshaling fails, return an error message
-		var ptr *T = &fallback
-		*unmarshalTo = *ptr
-		return fmt.Errorf("failed to unmarshal environment variable %s: %w", key, err)
+// valueOf returns v as the validators, the registry and Snapshot see it.
+func valueOf[T any](v T) any {
+	if rv, ok := any(v).(reflect.Value); ok {
+		return rv.Interface()
 	}
+	return v
+}
 
-	return nil
+// zeroOf returns the zero value of the type of v.
+func zeroOf[T any](v T) T {
+	if rv, ok := any(v).(reflect.Value); ok {
+		return any(reflect.Zero(rv.Type())).(T)
+	}
+	var zero T
+	return zero
 }
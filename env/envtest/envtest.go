@@ -0,0 +1,134 @@
+// Package envtest helps tests of code that reads its configuration with package env.
+//
+// Tests that can pass a reader around should use NewReader, which never touches the environment of the process and
+// is therefore safe in parallel tests. Code that reads the process environment directly can be tested with Setenv,
+// which applies overrides for the duration of a test, or With, which applies them for the duration of a function.
+// Both restore the previous values afterwards, including variables that were not set before.
+package envtest
+
+/*
+Copyright 2023 UMH Systems GmbH
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"testing"
+
+	"github.com/united-manufacturing-hub/umh-utils/env"
+)
+
+// Unset can be used as the value of an override to unset the variable. Environment variables cannot contain NUL
+// bytes, so it never collides with a real value.
+const Unset = "\x00"
+
+// Setenv applies vars to the environment of the process and restores the previous values when the test and its
+// subtests have finished. Like testing.T.Setenv, it cannot be used in parallel tests or tests with parallel
+// ancestors.
+func Setenv(t testing.TB, vars map[string]string) {
+	t.Helper()
+	for _, key := range sortedKeys(vars) {
+		// t.Setenv registers the restore and rejects parallel tests
+		t.Setenv(key, "")
+		if err := set(key, vars[key]); err != nil {
+			t.Fatalf("envtest: %v", err)
+		}
+	}
+}
+
+// With applies vars to the environment of the process, calls fn and restores the previous values, even if fn
+// panics. It must not be used while other goroutines read or change the environment.
+func With(vars map[string]string, fn func()) error {
+	restore, err := Apply(vars)
+	if err != nil {
+		return err
+	}
+	defer restore()
+	fn()
+	return nil
+}
+
+// Apply applies vars to the environment of the process and returns a function that restores the previous values.
+// It is meant for TestMain, where neither Setenv nor With fit. If an override cannot be applied, the ones applied
+// before are restored and an error is returned.
+func Apply(vars map[string]string) (restore func(), err error) {
+	type previous struct {
+		key   string
+		value string
+		ok    bool
+	}
+	var saved []previous
+	restore = func() {
+		for i := len(saved) - 1; i >= 0; i-- {
+			if saved[i].ok {
+				_ = os.Setenv(saved[i].key, saved[i].value)
+			} else {
+				_ = os.Unsetenv(saved[i].key)
+			}
+		}
+	}
+
+	for _, key := range sortedKeys(vars) {
+		value, ok := os.LookupEnv(key)
+		saved = append(saved, previous{key: key, value: value, ok: ok})
+		if err = set(key, vars[key]); err != nil {
+			restore()
+			return nil, err
+		}
+	}
+	return restore, nil
+}
+
+// Source returns vars as an in-memory source. Variables overridden with Unset are left out.
+func Source(vars map[string]string) env.MapSource {
+	source := make(env.MapSource, len(vars))
+	for key, value := range vars {
+		if value != Unset {
+			source[key] = value
+		}
+	}
+	return source
+}
+
+// NewReader returns a reader of vars that does not see the environment of the process, so that parallel tests
+// can each use their own values.
+func NewReader(vars map[string]string, opts ...env.Option) *env.Reader {
+	return env.NewReader(Source(vars), opts...)
+}
+
+// set sets or unsets a single variable.
+func set(key, value string) error {
+	var err error
+	if value == Unset {
+		err = os.Unsetenv(key)
+	} else {
+		err = os.Setenv(key, value)
+	}
+	if err != nil {
+		return fmt.Errorf("cannot set %s: %w", key, err)
+	}
+	return nil
+}
+
+// sortedKeys returns the keys of vars in order, so that overrides are applied deterministically.
+func sortedKeys(vars map[string]string) []string {
+	keys := make([]string, 0, len(vars))
+	for key := range vars {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
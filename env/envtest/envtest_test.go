@@ -0,0 +1,133 @@
+package envtest
+
+/*
+Copyright 2023 UMH Systems GmbH
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+import (
+	"os"
+	"testing"
+
+	"github.com/united-manufacturing-hub/umh-utils/env"
+)
+
+// lookup returns the value of key as "value" or "<unset>".
+func lookup(key string) string {
+	value, ok := os.LookupEnv(key)
+	if !ok {
+		return "<unset>"
+	}
+	return value
+}
+
+// TestSetenv tests that overrides last until the end of the test and that previous values are restored
+func TestSetenv(t *testing.T) {
+	t.Setenv("ENVTEST_EXISTING", "before")
+	t.Setenv("ENVTEST_REMOVED", "before")
+
+	t.Run("override", func(t *testing.T) {
+		Setenv(t, map[string]string{"ENVTEST_EXISTING": "during", "ENVTEST_NEW": "during", "ENVTEST_REMOVED": Unset})
+		tests := []struct {
+			name string
+			key  string
+			want string
+		}{
+			{name: "Case 1: Existing variables are overridden", key: "ENVTEST_EXISTING", want: "during"},
+			{name: "Case 2: New variables are set", key: "ENVTEST_NEW", want: "during"},
+			{name: "Case 3: Unset removes variables", key: "ENVTEST_REMOVED", want: "<unset>"},
+		}
+		for _, tt := range tests {
+			if got := lookup(tt.key); got != tt.want {
+				t.Errorf("%s: %s = %s, want %s", tt.name, tt.key, got, tt.want)
+			}
+		}
+		if got, err := env.GetAsString("ENVTEST_NEW", true, ""); err != nil || got != "during" {
+			t.Errorf("GetAsString() = %q, %v", got, err)
+		}
+	})
+
+	for key, want := range map[string]string{"ENVTEST_EXISTING": "before", "ENVTEST_NEW": "<unset>", "ENVTEST_REMOVED": "before"} {
+		if got := lookup(key); got != want {
+			t.Errorf("after the test %s = %s, want %s", key, got, want)
+		}
+	}
+}
+
+// TestWith tests that With restores the environment when the function returns or panics
+func TestWith(t *testing.T) {
+	t.Setenv("ENVTEST_WITH", "before")
+	vars := map[string]string{"ENVTEST_WITH": "during", "ENVTEST_WITH_NEW": "during"}
+
+	var seen string
+	if err := With(vars, func() { seen = lookup("ENVTEST_WITH") + " " + lookup("ENVTEST_WITH_NEW") }); err != nil {
+		t.Fatalf("With() error = %v", err)
+	}
+	if seen != "during during" {
+		t.Errorf("With() saw %q, want the overrides", seen)
+	}
+	if got := lookup("ENVTEST_WITH") + " " + lookup("ENVTEST_WITH_NEW"); got != "before <unset>" {
+		t.Errorf("after With() got %q, want the previous values", got)
+	}
+
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Error("With() did not propagate the panic")
+			}
+		}()
+		_ = With(vars, func() { panic("boom") })
+	}()
+	if got := lookup("ENVTEST_WITH") + " " + lookup("ENVTEST_WITH_NEW"); got != "before <unset>" {
+		t.Errorf("after a panic got %q, want the previous values", got)
+	}
+}
+
+// TestApplyError tests that a failed override restores the ones applied before
+func TestApplyError(t *testing.T) {
+	t.Setenv("ENVTEST_APPLY", "before")
+	restore, err := Apply(map[string]string{"ENVTEST_APPLY": "during", "ENVTEST_INVALID=": "x"})
+	if err == nil {
+		restore()
+		t.Fatal("Apply() error = nil, want an error for an invalid name")
+	}
+	if got := lookup("ENVTEST_APPLY"); got != "before" {
+		t.Errorf("after a failed Apply() ENVTEST_APPLY = %s, want before", got)
+	}
+}
+
+// TestNewReader tests that readers of overrides are independent of the process and of each other
+func TestNewReader(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name string
+		vars map[string]string
+		want string
+	}{
+		{name: "Case 1: Value is read", vars: map[string]string{"PATH": "/envtest"}, want: "/envtest"},
+		{name: "Case 2: Unset hides the process environment", vars: map[string]string{"PATH": Unset}, want: "fallback"},
+		{name: "Case 3: Missing variables are not set", vars: map[string]string{}, want: "fallback"},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			got, err := NewReader(tt.vars).GetAsString("PATH", false, "fallback")
+			if err != nil || got != tt.want {
+				t.Errorf("GetAsString() = %q, %v, want %q", got, err, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,230 @@
+package env
+
+/*
+Copyright 2023 UMH Systems GmbH
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// redacted replaces the value of sensitive variables in error messages.
+const redacted = "[REDACTED]"
+
+// ErrNotSet is matched by errors.Is for every error caused by a required environment variable that is not set.
+var ErrNotSet = errors.New("required environment variable is not set")
+
+// NotSetError is returned when a required environment variable is not set.
+type NotSetError struct {
+	// Key is the name of the environment variable.
+	Key string
+	// Type is the type the value would have been parsed into.
+	Type string
+	// Empty is set if the variable is set to an empty string, which PolicyRequiredStrict rejects.
+	Empty bool
+}
+
+func (e *NotSetError) Error() string {
+	if e.Empty {
+		return fmt.Sprintf("environment variable %s is required but empty (expected %s)", e.Key, e.Type)
+	}
+	return fmt.Sprintf("environment variable %s is required but not set (expected %s)", e.Key, e.Type)
+}
+
+// Is makes errors.Is(err, ErrNotSet) report true.
+func (e *NotSetError) Is(target error) bool {
+	return target == ErrNotSet
+}
+
+// ParseError is returned when the value of an environment variable cannot be parsed into the requested type.
+type ParseError struct {
+	// Err is the underlying error of the parser.
+	Err error
+	// Key is the name of the environment variable.
+	Key string
+	// Value is the raw value of the environment variable.
+	Value string
+	// Type is the type the value should have been parsed into.
+	Type string
+	// Sensitive hides Value from the error message.
+	Sensitive bool
+}
+
+func (e *ParseError) Error() string {
+	value := e.Value
+	reason := e.Err.Error()
+	if e.Sensitive {
+		value = redacted
+		if e.Value != "" {
+			reason = strings.ReplaceAll(reason, e.Value, redacted)
+		}
+	}
+	return fmt.Sprintf("environment variable %s has value %q, which is not a valid %s: %s", e.Key, value, e.Type, reason)
+}
+
+func (e *ParseError) Unwrap() error {
+	return e.Err
+}
+
+// newParseError wraps err for the variable key.
+func newParseError(key, value, typ string, err error, o *options) *ParseError {
+	return &ParseError{Key: key, Value: value, Type: typ, Err: plainError(err), Sensitive: o.sensitive}
+}
+
+// plainError drops the prefix of a *strconv.NumError, which would repeat the raw value in the message.
+func plainError(err error) error {
+	var numErr *strconv.NumError
+	if errors.As(err, &numErr) && err == error(numErr) {
+		return numErr.Err
+	}
+	return err
+}
+
+// ValidationError is returned when the value of an environment variable was parsed, but violates a validation
+// rule such as Min or OneOf.
+type ValidationError struct {
+	// Err describes the violation.
+	Err error
+	// Key is the name of the environment variable.
+	Key string
+	// Value is the raw value of the environment variable.
+	Value string
+	// Rule names the violated rule, e.g. "max=65535".
+	Rule string
+	// Sensitive hides Value from the error message.
+	Sensitive bool
+}
+
+func (e *ValidationError) Error() string {
+	value := e.Value
+	reason := e.Err.Error()
+	if e.Sensitive {
+		value = redacted
+		if e.Value != "" {
+			reason = strings.ReplaceAll(reason, e.Value, redacted)
+		}
+	}
+	return fmt.Sprintf("environment variable %s has value %q, which violates rule %s: %s", e.Key, value, e.Rule, reason)
+}
+
+func (e *ValidationError) Unwrap() error {
+	return e.Err
+}
+
+// ElementError is wrapped by a *ParseError when a single element of a list or map cannot be parsed.
+type ElementError struct {
+	// Err is the error of the element parser.
+	Err error
+	// Value is the raw element.
+	Value string
+	// Index is the zero-based position of the element.
+	Index int
+}
+
+// newElementError wraps err for the element at index.
+func newElementError(index int, value string, err error) *ElementError {
+	return &ElementError{Index: index, Value: value, Err: plainError(err)}
+}
+
+func (e *ElementError) Error() string {
+	return fmt.Sprintf("element %d: %v", e.Index, e.Err)
+}
+
+func (e *ElementError) Unwrap() error {
+	return e.Err
+}
+
+// RangeError is wrapped by a *ParseError when an integer does not fit into the requested type. It matches
+// strconv.ErrRange with errors.Is.
+type RangeError struct {
+	// Min is the smallest allowed value.
+	Min int64
+	// Max is the largest allowed value.
+	Max uint64
+}
+
+func (e *RangeError) Error() string {
+	return fmt.Sprintf("value out of range, must be between %d and %d", e.Min, e.Max)
+}
+
+func (e *RangeError) Unwrap() error {
+	return strconv.ErrRange
+}
+
+// ErrFileConflict is returned when both KEY and KEY_FILE are set, as it is unclear which one should be used.
+var ErrFileConflict = errors.New("only one of KEY and KEY_FILE may be set")
+
+// FileError is returned when the file named by a KEY_FILE variable cannot be read.
+type FileError struct {
+	// Err is the error returned when reading the file.
+	Err error
+	// Key is the name of the variable the file holds the value of, without the _FILE suffix.
+	Key string
+	// Path is the path of the file.
+	Path string
+}
+
+func (e *FileError) Error() string {
+	return fmt.Sprintf("environment variable %s could not be read from file %s: %v", e.Key, e.Path, e.Err)
+}
+
+func (e *FileError) Unwrap() error {
+	return e.Err
+}
+
+// Errors is a list of errors from several lookups, reported together.
+type Errors []error
+
+// Error lists every collected error on its own line.
+func (e Errors) Error() string {
+	if len(e) == 1 {
+		return e[0].Error()
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "%d environment variable errors:", len(e))
+	for _, err := range e {
+		b.WriteString("\n\t- ")
+		b.WriteString(err.Error())
+	}
+	return b.String()
+}
+
+// Unwrap returns the collected errors.
+func (e Errors) Unwrap() []error {
+	return e
+}
+
+// Is reports whether any of the collected errors matches target.
+func (e Errors) Is(target error) bool {
+	for _, err := range e {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// As finds the first collected error that matches target.
+func (e Errors) As(target any) bool {
+	for _, err := range e {
+		if errors.As(err, target) {
+			return true
+		}
+	}
+	return false
+}
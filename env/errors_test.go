@@ -0,0 +1,73 @@
+package env
+
+/*
+Copyright 2023 UMH Systems GmbH
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+import (
+	"errors"
+	"strconv"
+	"testing"
+)
+
+// TestErrorTypes tests that missing and unparsable variables can be told apart with errors.Is and errors.As
+func TestErrorTypes(t *testing.T) {
+	t.Setenv("ERRORS_WRONG_VAR", "wrong")
+
+	_, err := GetAsInt("ERRORS_NONEXISTENT_VAR", true, 0)
+	if !errors.Is(err, ErrNotSet) {
+		t.Errorf("GetAsInt() error = %v, want ErrNotSet", err)
+	}
+	var notSet *NotSetError
+	if !errors.As(err, &notSet) || notSet.Key != "ERRORS_NONEXISTENT_VAR" || notSet.Type != "int" {
+		t.Errorf("GetAsInt() error = %#v, want *NotSetError for ERRORS_NONEXISTENT_VAR", err)
+	}
+
+	_, err = GetAsInt("ERRORS_WRONG_VAR", false, 0)
+	if errors.Is(err, ErrNotSet) {
+		t.Errorf("GetAsInt() error = %v, must not match ErrNotSet", err)
+	}
+	var parseErr *ParseError
+	if !errors.As(err, &parseErr) {
+		t.Fatalf("GetAsInt() error = %v, want *ParseError", err)
+	}
+	if parseErr.Key != "ERRORS_WRONG_VAR" || parseErr.Value != "wrong" || parseErr.Type != "int" {
+		t.Errorf("GetAsInt() error = %#v, want key, value and type to be set", parseErr)
+	}
+	if !errors.Is(err, strconv.ErrSyntax) {
+		t.Errorf("GetAsInt() error = %v, want it to wrap strconv.ErrSyntax", err)
+	}
+
+	var target struct{}
+	err = GetAsType("ERRORS_WRONG_VAR", &target, false, struct{}{})
+	if !errors.As(err, &parseErr) {
+		t.Errorf("GetAsType() error = %v, want *ParseError", err)
+	}
+}
+
+// TestErrorsIs tests that errors.Is and errors.As look into collected errors
+func TestErrorsIs(t *testing.T) {
+	errs := Errors{
+		&ParseError{Key: "A", Value: "x", Type: "int", Err: strconv.ErrSyntax},
+		&NotSetError{Key: "B", Type: "string"},
+	}
+	if !errors.Is(errs, ErrNotSet) {
+		t.Errorf("errors.Is(%v, ErrNotSet) = false, want true", errs)
+	}
+	var notSet *NotSetError
+	if !errors.As(errs, &notSet) || notSet.Key != "B" {
+		t.Errorf("errors.As(%v) did not find the *NotSetError", errs)
+	}
+}
@@ -0,0 +1,211 @@
+package env
+
+/*
+Copyright 2023 UMH Systems GmbH
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+// Format is the syntax of a structured value, see WithFormat.
+type Format string
+
+const (
+	FormatJSON = Format("json") // JSON, the default
+	FormatYAML = Format("yaml") // YAML, e.g. a snippet pasted from a Helm chart
+	FormatTOML = Format("toml") // TOML
+	FormatAuto = Format("auto") // detected from the value
+)
+
+var (
+	// tomlLine matches the first line of a TOML document: a table header or a key/value pair.
+	tomlLine = regexp.MustCompile(`^(\[\[?\s*[\w."' -]+\s*\]\]?|[\w."'-]+\s*=)`)
+	// yamlErrorLine extracts the line number from the errors of the YAML parser.
+	yamlErrorLine = regexp.MustCompile(`^yaml: line (\d+): (.*)$`)
+)
+
+// SyntaxError is wrapped by a *ParseError when a structured value is not well-formed.
+type SyntaxError struct {
+	// Err describes the problem.
+	Err error
+	// Format is the syntax the value was parsed as.
+	Format Format
+	// Line is the line of the problem, starting at 1.
+	Line int
+	// Column is the column of the problem, starting at 1. It is 0 if the parser does not report it, as for YAML.
+	Column int
+}
+
+func (e *SyntaxError) Error() string {
+	if e.Column == 0 {
+		return fmt.Sprintf("%s syntax error at line %d: %v", e.Format, e.Line, e.Err)
+	}
+	return fmt.Sprintf("%s syntax error at line %d, column %d: %v", e.Format, e.Line, e.Column, e.Err)
+}
+
+func (e *SyntaxError) Unwrap() error {
+	return e.Err
+}
+
+// WithFormat sets the syntax of structured values decoded by GetAsType and Load. The default is FormatJSON;
+// FormatAuto detects JSON, TOML and YAML. YAML and TOML values are decoded into the same types as JSON values,
+// following their json struct tags.
+func WithFormat(f Format) Option {
+	return func(o *options) {
+		o.format = f
+	}
+}
+
+// decodeValue unmarshals raw in the format set by o into the value target points to.
+func decodeValue(raw string, target any, o *options) error {
+	format := o.format
+	if format == FormatAuto {
+		format = detectFormat(raw)
+	}
+
+	switch format {
+	case FormatJSON, "":
+		return jsonSyntaxError(decodeJSON(raw, target, o), raw)
+	case FormatYAML:
+		var tree any
+		if err := yaml.Unmarshal([]byte(raw), &tree); err != nil {
+			return yamlSyntaxError(err)
+		}
+		return decodeTree(normalizeYAML(tree), target, o)
+	case FormatTOML:
+		var tree map[string]any
+		if _, err := toml.Decode(raw, &tree); err != nil {
+			return tomlSyntaxError(err, raw)
+		}
+		return decodeTree(tree, target, o)
+	default:
+		return fmt.Errorf("unknown format %q", format)
+	}
+}
+
+// decodeTree decodes a generic tree by way of JSON, so that json struct tags, Merge and DisallowUnknownFields apply.
+func decodeTree(tree any, target any, o *options) error {
+	data, err := json.Marshal(tree)
+	if err != nil {
+		return err
+	}
+	return decodeJSON(string(data), target, o)
+}
+
+// detectFormat guesses the syntax of raw. Valid JSON is JSON, a document starting with a TOML table header or
+// key/value pair is TOML, a document starting with a bracket is broken JSON and everything else is YAML.
+func detectFormat(raw string) Format {
+	if json.Valid([]byte(raw)) {
+		return FormatJSON
+	}
+	for _, line := range strings.Split(raw, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		switch {
+		case tomlLine.MatchString(line):
+			return FormatTOML
+		case strings.HasPrefix(line, "{") || strings.HasPrefix(line, "["):
+			return FormatJSON
+		default:
+			return FormatYAML
+		}
+	}
+	return FormatJSON
+}
+
+// normalizeYAML converts the maps decoded by YAML, which may have keys of any type, into JSON objects.
+func normalizeYAML(tree any) any {
+	switch v := tree.(type) {
+	case map[string]any:
+		for key, value := range v {
+			v[key] = normalizeYAML(value)
+		}
+		return v
+	case map[any]any:
+		m := make(map[string]any, len(v))
+		for key, value := range v {
+			m[fmt.Sprint(key)] = normalizeYAML(value)
+		}
+		return m
+	case []any:
+		for i, value := range v {
+			v[i] = normalizeYAML(value)
+		}
+		return v
+	default:
+		return v
+	}
+}
+
+// jsonSyntaxError converts a syntax error of encoding/json into a *SyntaxError.
+func jsonSyntaxError(err error, raw string) error {
+	var syntaxErr *json.SyntaxError
+	if !errors.As(err, &syntaxErr) {
+		return err
+	}
+	// Offset counts the bytes read, including the offending one
+	line, column := position(raw, int(syntaxErr.Offset)-1)
+	return &SyntaxError{Format: FormatJSON, Line: line, Column: column, Err: errors.New(strings.TrimPrefix(syntaxErr.Error(), "json: "))}
+}
+
+// yamlSyntaxError converts an error of the YAML parser into a *SyntaxError.
+func yamlSyntaxError(err error) error {
+	match := yamlErrorLine.FindStringSubmatch(err.Error())
+	if match == nil {
+		return err
+	}
+	line, convErr := strconv.Atoi(match[1])
+	if convErr != nil {
+		return err
+	}
+	return &SyntaxError{Format: FormatYAML, Line: line, Err: errors.New(match[2])}
+}
+
+// tomlSyntaxError converts an error of the TOML parser into a *SyntaxError.
+func tomlSyntaxError(err error, raw string) error {
+	var parseErr toml.ParseError
+	if !errors.As(err, &parseErr) {
+		return err
+	}
+	msg := parseErr.Message
+	if msg == "" {
+		msg = parseErr.Error()
+	}
+	_, column := position(raw, parseErr.Position.Start)
+	return &SyntaxError{Format: FormatTOML, Line: parseErr.Position.Line, Column: column, Err: errors.New(msg)}
+}
+
+// position returns the line and column of the byte at offset, both starting at 1.
+func position(raw string, offset int) (line, column int) {
+	if offset < 0 {
+		offset = 0
+	}
+	if offset > len(raw) {
+		offset = len(raw)
+	}
+	before := raw[:offset]
+	return strings.Count(before, "\n") + 1, offset - strings.LastIndex(before, "\n")
+}
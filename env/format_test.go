@@ -0,0 +1,155 @@
+package env
+
+/*
+Copyright 2023 UMH Systems GmbH
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+type formatTestConverter struct {
+	Name     string            `json:"name"`
+	Topics   []string          `json:"topics"`
+	Labels   map[string]string `json:"labels"`
+	Interval int               `json:"interval"`
+	Enabled  bool              `json:"enabled"`
+}
+
+// TestFormats tests decoding structured values as JSON, YAML and TOML
+func TestFormats(t *testing.T) {
+	t.Parallel()
+
+	want := formatTestConverter{Name: "opcua", Topics: []string{"a", "b"}, Labels: map[string]string{"site": "aachen"}, Interval: 5, Enabled: true}
+	r := NewReader(MapSource{
+		"FORMAT_JSON":       `{"name":"opcua","topics":["a","b"],"labels":{"site":"aachen"},"interval":5,"enabled":true}`,
+		"FORMAT_YAML":       "# pasted from values.yaml\nname: opcua\ntopics:\n  - a\n  - b\nlabels:\n  site: aachen\ninterval: 5\nenabled: true\n",
+		"FORMAT_TOML":       "name = \"opcua\"\ntopics = [\"a\", \"b\"]\ninterval = 5\nenabled = true\n\n[labels]\nsite = \"aachen\"\n",
+		"FORMAT_TOML_TABLE": "[labels]\nsite = \"aachen\"\n",
+		"FORMAT_YAML_FLOW":  "{name: opcua, topics: [a, b], labels: {site: aachen}, interval: 5, enabled: true}",
+	})
+
+	tests := []struct {
+		name   string
+		key    string
+		format Format
+		want   formatTestConverter
+	}{
+		{name: "Case 1: JSON by default", key: "FORMAT_JSON", want: want},
+		{name: "Case 2: Declared YAML", key: "FORMAT_YAML", format: FormatYAML, want: want},
+		{name: "Case 3: Declared TOML", key: "FORMAT_TOML", format: FormatTOML, want: want},
+		{name: "Case 4: Detected JSON", key: "FORMAT_JSON", format: FormatAuto, want: want},
+		{name: "Case 5: Detected YAML", key: "FORMAT_YAML", format: FormatAuto, want: want},
+		{name: "Case 6: Detected TOML", key: "FORMAT_TOML", format: FormatAuto, want: want},
+		{name: "Case 7: Detected TOML table", key: "FORMAT_TOML_TABLE", format: FormatAuto, want: formatTestConverter{Labels: map[string]string{"site": "aachen"}}},
+		{name: "Case 8: YAML is a superset of JSON", key: "FORMAT_JSON", format: FormatYAML, want: want},
+		{name: "Case 9: YAML flow style", key: "FORMAT_YAML_FLOW", format: FormatYAML, want: want},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			var opts []Option
+			if tt.format != "" {
+				opts = append(opts, WithFormat(tt.format))
+			}
+			var got formatTestConverter
+			if err := GetAsTypeFrom(r, tt.key, &got, true, formatTestConverter{}, opts...); err != nil {
+				t.Fatalf("GetAsTypeFrom() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("GetAsTypeFrom() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestFormatErrors tests that syntax errors report their position and that the fallback is kept
+func TestFormatErrors(t *testing.T) {
+	t.Parallel()
+
+	r := NewReader(MapSource{
+		"FORMAT_BAD_JSON":  "{\n  \"name\": x\n}",
+		"FORMAT_BAD_YAML":  "name: opcua\ninterval: 5\n  enabled: true\n",
+		"FORMAT_BAD_TOML":  "name = \"opcua\"\ninterval = = 5\n",
+		"FORMAT_BAD_TYPE":  "interval: five\n",
+		"FORMAT_BAD_FIELD": "name: opcua\nintervall: 5\n",
+	})
+	fallback := formatTestConverter{Name: "fallback"}
+
+	tests := []struct {
+		name       string
+		key        string
+		format     Format
+		opts       []Option
+		wantFormat Format
+		wantLine   int
+		wantColumn int
+		wantPath   string
+	}{
+		{name: "Case 1: JSON", key: "FORMAT_BAD_JSON", format: FormatJSON, wantFormat: FormatJSON, wantLine: 2, wantColumn: 11},
+		{name: "Case 2: Detected broken JSON", key: "FORMAT_BAD_JSON", format: FormatAuto, wantFormat: FormatJSON, wantLine: 2, wantColumn: 11},
+		{name: "Case 3: YAML", key: "FORMAT_BAD_YAML", format: FormatYAML, wantFormat: FormatYAML, wantLine: 3},
+		{name: "Case 4: TOML", key: "FORMAT_BAD_TOML", format: FormatTOML, wantFormat: FormatTOML, wantLine: 2, wantColumn: 12},
+		{name: "Case 5: Type errors carry the path", key: "FORMAT_BAD_TYPE", format: FormatYAML, wantPath: "interval"},
+		{name: "Case 6: Unknown fields carry the path", key: "FORMAT_BAD_FIELD", format: FormatYAML, opts: []Option{DisallowUnknownFields()}, wantPath: "intervall"},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			got := formatTestConverter{Name: "unchanged"}
+			err := GetAsTypeFrom(r, tt.key, &got, true, fallback, append(tt.opts, WithFormat(tt.format))...)
+			var parseErr *ParseError
+			if !errors.As(err, &parseErr) {
+				t.Fatalf("GetAsTypeFrom() error = %v, want *ParseError", err)
+			}
+			if !reflect.DeepEqual(got, fallback) {
+				t.Errorf("GetAsTypeFrom() = %+v, want the fallback", got)
+			}
+			if tt.wantPath != "" {
+				var jsonErr *JSONError
+				if !errors.As(err, &jsonErr) || jsonErr.Path != tt.wantPath {
+					t.Errorf("GetAsTypeFrom() error = %v, want *JSONError for %s", err, tt.wantPath)
+				}
+				return
+			}
+			var syntaxErr *SyntaxError
+			if !errors.As(err, &syntaxErr) {
+				t.Fatalf("GetAsTypeFrom() error = %v, want *SyntaxError", err)
+			}
+			if syntaxErr.Format != tt.wantFormat || syntaxErr.Line != tt.wantLine || syntaxErr.Column != tt.wantColumn {
+				t.Errorf("SyntaxError = %s at %d:%d, want %s at %d:%d (%v)", syntaxErr.Format, syntaxErr.Line, syntaxErr.Column, tt.wantFormat, tt.wantLine, tt.wantColumn, err)
+			}
+		})
+	}
+}
+
+// TestLoadFormat tests that struct fields can be decoded from YAML
+func TestLoadFormat(t *testing.T) {
+	t.Parallel()
+
+	r := NewReader(MapSource{"FORMAT_CONVERTER": "name: opcua\ninterval: 5\n"})
+	var cfg struct {
+		Converter formatTestConverter `env:"FORMAT_CONVERTER"`
+	}
+	if err := r.Load(&cfg, WithFormat(FormatAuto)); err != nil || cfg.Converter.Name != "opcua" || cfg.Converter.Interval != 5 {
+		t.Errorf("Load() = %+v, %v", cfg, err)
+	}
+}
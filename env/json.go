@@ -0,0 +1,212 @@
+package env
+
+/*
+Copyright 2023 UMH Systems GmbH
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+var jsonUnmarshalerType = reflect.TypeOf((*json.Unmarshaler)(nil)).Elem()
+
+// JSONError is wrapped by a *ParseError when a field of a JSON value cannot be decoded.
+type JSONError struct {
+	// Err describes the problem.
+	Err error
+	// Path is the dot-separated path of the field, e.g. "kafka.brokers.0". Array elements are named by their index.
+	Path string
+}
+
+func (e *JSONError) Error() string {
+	return fmt.Sprintf("field %s: %v", e.Path, e.Err)
+}
+
+func (e *JSONError) Unwrap() error {
+	return e.Err
+}
+
+// Merge makes GetAsType decode the JSON value onto a copy of the fallback, so that the value only overrides the
+// fields it mentions. The fallback is copied through JSON, so only its exported fields are kept.
+func Merge() Option {
+	return func(o *options) {
+		o.merge = true
+	}
+}
+
+// DisallowUnknownFields rejects JSON objects with fields that the target struct does not have.
+func DisallowUnknownFields() Option {
+	return func(o *options) {
+		o.disallowUnknownFields = true
+	}
+}
+
+// decodeJSON unmarshals raw into the value target points to, as configured by o. Errors in a nested field are
+// returned as *JSONError.
+func decodeJSON(raw string, target any, o *options) error {
+	if o.disallowUnknownFields {
+		var tree any
+		if err := json.Unmarshal([]byte(raw), &tree); err != nil {
+			return err
+		}
+		if err := checkUnknownFields(tree, reflect.TypeOf(target).Elem(), ""); err != nil {
+			return err
+		}
+	}
+
+	err := json.Unmarshal([]byte(raw), target)
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &typeErr) && typeErr.Field != "" {
+		return &JSONError{Path: typeErr.Field, Err: fmt.Errorf("cannot unmarshal %s into %s", typeErr.Value, typeErr.Type)}
+	}
+	return err
+}
+
+// copyJSON returns a deep copy of v, made by encoding it to JSON and back.
+func copyJSON[T any](v T) (T, error) {
+	var c T
+	data, err := json.Marshal(v)
+	if err != nil {
+		return c, fmt.Errorf("cannot copy the fallback value: %w", err)
+	}
+	if err = json.Unmarshal(data, &c); err != nil {
+		return c, fmt.Errorf("cannot copy the fallback value: %w", err)
+	}
+	return c, nil
+}
+
+// checkUnknownFields walks the decoded JSON tree alongside type t and reports the first object field that t does
+// not have. Types that decode themselves are not checked.
+func checkUnknownFields(tree any, t reflect.Type, path string) error {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	pt := reflect.PointerTo(t)
+	if pt.Implements(jsonUnmarshalerType) || pt.Implements(textUnmarshalerType) {
+		return nil
+	}
+
+	switch t.Kind() {
+	case reflect.Struct:
+		object, ok := tree.(map[string]any)
+		if !ok {
+			return nil
+		}
+		fields := jsonFields(t)
+		for _, key := range sortedKeys(object) {
+			field, ok := lookupJSONField(fields, key)
+			if !ok {
+				return &JSONError{Path: joinPath(path, key), Err: errors.New("unknown field")}
+			}
+			if err := checkUnknownFields(object[key], field, joinPath(path, key)); err != nil {
+				return err
+			}
+		}
+	case reflect.Map:
+		object, ok := tree.(map[string]any)
+		if !ok {
+			return nil
+		}
+		for _, key := range sortedKeys(object) {
+			if err := checkUnknownFields(object[key], t.Elem(), joinPath(path, key)); err != nil {
+				return err
+			}
+		}
+	case reflect.Slice, reflect.Array:
+		array, ok := tree.([]any)
+		if !ok {
+			return nil
+		}
+		for i, value := range array {
+			if err := checkUnknownFields(value, t.Elem(), joinPath(path, strconv.Itoa(i))); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// jsonFields returns the types of the fields of struct t by their JSON name, including promoted fields of embedded
+// structs.
+func jsonFields(t reflect.Type) map[string]reflect.Type {
+	fields := make(map[string]reflect.Type)
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+		if field.Anonymous && name == "" {
+			embedded := field.Type
+			if embedded.Kind() == reflect.Pointer {
+				embedded = embedded.Elem()
+			}
+			if embedded.Kind() == reflect.Struct {
+				for n, typ := range jsonFields(embedded) {
+					if _, shadowed := fields[n]; !shadowed {
+						fields[n] = typ
+					}
+				}
+				continue
+			}
+		}
+		if !field.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		fields[name] = field.Type
+	}
+	return fields
+}
+
+// lookupJSONField finds the field for key, preferring an exact match over a case-insensitive one, like
+// encoding/json.
+func lookupJSONField(fields map[string]reflect.Type, key string) (reflect.Type, bool) {
+	if t, ok := fields[key]; ok {
+		return t, true
+	}
+	for name, t := range fields {
+		if strings.EqualFold(name, key) {
+			return t, true
+		}
+	}
+	return nil, false
+}
+
+// sortedKeys returns the keys of a JSON object in order, so that the first unknown field is reported reliably.
+func sortedKeys(object map[string]any) []string {
+	keys := make([]string, 0, len(object))
+	for key := range object {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// joinPath appends name to a dot-separated path.
+func joinPath(path, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + "." + name
+}
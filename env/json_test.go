@@ -0,0 +1,146 @@
+package env
+
+/*
+Copyright 2023 UMH Systems GmbH
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+type jsonTestEmbedded struct {
+	Region string `json:"region"`
+}
+
+type jsonTestKafka struct {
+	Brokers []string          `json:"brokers"`
+	Labels  map[string]string `json:"labels"`
+	Retries int               `json:"retries"`
+}
+
+type jsonTestConfig struct {
+	jsonTestEmbedded
+	Kafka   jsonTestKafka   `json:"kafka"`
+	Topics  []jsonTestKafka `json:"topics"`
+	Name    string          `json:"name"`
+	Ignored string          `json:"-"`
+	Enabled bool
+}
+
+func jsonTestFallback() jsonTestConfig {
+	return jsonTestConfig{
+		jsonTestEmbedded: jsonTestEmbedded{Region: "eu"},
+		Kafka:            jsonTestKafka{Brokers: []string{"localhost:9092"}, Labels: map[string]string{"a": "1"}, Retries: 3},
+		Name:             "umh",
+		Enabled:          true,
+	}
+}
+
+// TestGetAsTypeMerge tests that Merge only overrides the fields mentioned by the value
+func TestGetAsTypeMerge(t *testing.T) {
+	t.Parallel()
+
+	r := NewReader(MapSource{
+		"JSON_PARTIAL": `{"kafka":{"retries":5,"labels":{"b":"2"}}}`,
+		"JSON_WRONG":   `{"kafka":{"retries":"five"}}`,
+	})
+
+	fallback := jsonTestFallback()
+	var got jsonTestConfig
+	if err := GetAsTypeFrom(r, "JSON_PARTIAL", &got, true, fallback, Merge()); err != nil {
+		t.Fatalf("GetAsTypeFrom() error = %v", err)
+	}
+	want := jsonTestFallback()
+	want.Kafka.Retries = 5
+	want.Kafka.Labels = map[string]string{"a": "1", "b": "2"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("GetAsTypeFrom() = %+v, want %+v", got, want)
+	}
+	if !reflect.DeepEqual(fallback, jsonTestFallback()) {
+		t.Errorf("GetAsTypeFrom() modified the fallback: %+v", fallback)
+	}
+
+	// Without Merge, the value replaces everything it does not mention
+	got = jsonTestConfig{}
+	if err := GetAsTypeFrom(r, "JSON_PARTIAL", &got, true, fallback); err != nil {
+		t.Fatalf("GetAsTypeFrom() error = %v", err)
+	}
+	if got.Name != "" || got.Kafka.Retries != 5 || len(got.Kafka.Brokers) != 0 {
+		t.Errorf("GetAsTypeFrom() = %+v, want only the value", got)
+	}
+
+	// A failed merge returns the fallback
+	got = jsonTestConfig{}
+	err := GetAsTypeFrom(r, "JSON_WRONG", &got, true, fallback, Merge())
+	var jsonErr *JSONError
+	if !errors.As(err, &jsonErr) || jsonErr.Path != "kafka.retries" {
+		t.Errorf("GetAsTypeFrom() error = %v, want *JSONError for kafka.retries", err)
+	}
+	if !reflect.DeepEqual(got, jsonTestFallback()) {
+		t.Errorf("GetAsTypeFrom() = %+v, want the fallback", got)
+	}
+}
+
+// TestDisallowUnknownFields tests that unknown fields are reported with their JSON path
+func TestDisallowUnknownFields(t *testing.T) {
+	t.Parallel()
+
+	r := NewReader(MapSource{
+		"JSON_VALID":          `{"name":"x","REGION":"us","enabled":false,"kafka":{"Brokers":["a"]},"topics":[{"retries":1}]}`,
+		"JSON_UNKNOWN":        `{"name":"x","nmae":"y"}`,
+		"JSON_UNKNOWN_NESTED": `{"kafka":{"retries":1,"retry":2}}`,
+		"JSON_UNKNOWN_ARRAY":  `{"topics":[{"retries":1},{"brokers":[],"partitions":3}]}`,
+		"JSON_IGNORED":        `{"Ignored":"x"}`,
+	})
+
+	tests := []struct {
+		name     string
+		key      string
+		wantPath string
+	}{
+		{name: "Case 1: Known fields, case-insensitive and embedded", key: "JSON_VALID"},
+		{name: "Case 2: Unknown top-level field", key: "JSON_UNKNOWN", wantPath: "nmae"},
+		{name: "Case 3: Unknown nested field", key: "JSON_UNKNOWN_NESTED", wantPath: "kafka.retry"},
+		{name: "Case 4: Unknown field in an array element", key: "JSON_UNKNOWN_ARRAY", wantPath: "topics.1.partitions"},
+		{name: "Case 5: Fields excluded with json:\"-\" are unknown", key: "JSON_IGNORED", wantPath: "Ignored"},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			var cfg jsonTestConfig
+			err := GetAsTypeFrom(r, tt.key, &cfg, true, jsonTestConfig{}, DisallowUnknownFields())
+			if tt.wantPath == "" {
+				if err != nil {
+					t.Errorf("GetAsTypeFrom() error = %v", err)
+				}
+				return
+			}
+			var jsonErr *JSONError
+			if !errors.As(err, &jsonErr) || jsonErr.Path != tt.wantPath {
+				t.Errorf("GetAsTypeFrom() error = %v, want unknown field %s", err, tt.wantPath)
+			}
+		})
+	}
+
+	// Without the option, unknown fields are ignored
+	var cfg jsonTestConfig
+	if err := GetAsTypeFrom(r, "JSON_UNKNOWN", &cfg, true, jsonTestConfig{}); err != nil {
+		t.Errorf("GetAsTypeFrom() error = %v", err)
+	}
+}
@@ -0,0 +1,291 @@
+package env
+
+/*
+Copyright 2023 UMH Systems GmbH
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+)
+
+// flagTag overrides the name of the command-line flag of a field, e.g. `flag:"brokers"`. `flag:"-"` gives the field
+// no flag.
+const flagTag = "flag"
+
+// Layers are the sources of LoadLayered. Each one overrides the ones before it, and all of them override the
+// defaults.
+type Layers struct {
+	// File is a configuration file in JSON, YAML or TOML, which maps variable names to values. It is optional.
+	File string
+	// Env is the environment. The default is OSSource.
+	Env Source
+	// Flags are the parsed command-line flags, e.g. flag.CommandLine. Only flags that were set count. It is optional.
+	Flags *flag.FlagSet
+}
+
+// LoadLayered fills the struct pointed to by cfg like Load, but from several layers: the default tags first, then
+// layers.File, then layers.Env and finally layers.Flags. Flags are named after the field's `flag:"..."` tag, or
+// after its variable as returned by FlagName; RegisterFlags defines them. KEY_FILE is resolved within each layer, so
+// a layer that sets KEY or KEY_FILE overrides both of them in the layers below.
+//
+// The returned snapshot tells which layer each value came from: OriginDefault, OriginConfig, OriginEnv (or
+// OriginDotenv and OriginFile, as usual) or OriginFlag.
+//
+//	fs := flag.NewFlagSet("tool", flag.ExitOnError)
+//	if err := env.RegisterFlags(fs, &cfg); err != nil {
+//		return err
+//	}
+//	_ = fs.Parse(os.Args[1:])
+//	snapshot, err := env.LoadLayered(&cfg, env.Layers{File: "config.yaml", Flags: fs})
+func LoadLayered(cfg any, layers Layers, opts ...Option) (Snapshot, error) {
+	names, err := flagNames(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	var chain ChainSource
+	if layers.Flags != nil {
+		chain = append(chain, FlagSource{FlagSet: layers.Flags, Names: names})
+	}
+	if layers.Env != nil {
+		chain = append(chain, layers.Env)
+	} else {
+		chain = append(chain, OSSource{})
+	}
+	if layers.File != "" {
+		file, err := ReadConfigFile(layers.File)
+		if err != nil {
+			return nil, err
+		}
+		chain = append(chain, file)
+	}
+
+	r := NewReader(chain, opts...)
+	err = r.Load(cfg)
+	return r.Snapshot(), err
+}
+
+// RegisterFlags defines a flag on fs for every env-tagged field of the struct pointed to by cfg, so that
+// LoadLayered can read it. The flags take any text, which is parsed like the variable when loading; their usage
+// is the desc tag and their default the default tag. Flags that fs already has are left alone.
+func RegisterFlags(fs *flag.FlagSet, cfg any) error {
+	v := reflect.ValueOf(cfg)
+	if v.Kind() != reflect.Pointer || v.IsNil() || v.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("env.RegisterFlags expects a non-nil pointer to a struct, got %T", cfg)
+	}
+	return walkFlags(v.Elem().Type(), func(field reflect.StructField, key, name string) {
+		if name == "" || fs.Lookup(name) != nil {
+			return
+		}
+		value := &flagValue{isBool: field.Type.Kind() == reflect.Bool}
+		usage := field.Tag.Get(descTag)
+		if usage == "" {
+			usage = "sets " + key
+		}
+		fs.Var(value, name, usage)
+		if def, ok := field.Tag.Lookup(defaultTag); ok {
+			fs.Lookup(name).DefValue = def
+		}
+	})
+}
+
+// FlagName returns the flag name derived from a variable name: KAFKA_BROKERS becomes kafka-brokers.
+func FlagName(key string) string {
+	return strings.ReplaceAll(strings.ToLower(key), "_", "-")
+}
+
+// FlagSource reads variables from the flags of a FlagSet that were set on the command line. Flags that were not set
+// are reported as not set, so that the layers below them apply.
+type FlagSource struct {
+	// FlagSet holds the parsed flags.
+	FlagSet *flag.FlagSet
+	// Names maps variables to flag names. Variables that are not listed use FlagName, and an empty name means that
+	// the variable has no flag.
+	Names map[string]string
+}
+
+// Lookup returns the value of the flag for key, if it was set.
+func (s FlagSource) Lookup(key string) (string, bool, error) {
+	name := s.name(key)
+	if name == "" || s.FlagSet == nil {
+		return "", false, nil
+	}
+	var value string
+	set := false
+	s.FlagSet.Visit(func(f *flag.Flag) {
+		if f.Name == name {
+			value, set = f.Value.String(), true
+		}
+	})
+	return value, set, nil
+}
+
+// Origin reports every variable as OriginFlag, together with its flag.
+func (s FlagSource) Origin(key string) (Origin, string) {
+	return OriginFlag, "-" + s.name(key)
+}
+
+// name returns the flag name for key.
+func (s FlagSource) name(key string) string {
+	if name, ok := s.Names[key]; ok {
+		return name
+	}
+	return FlagName(key)
+}
+
+// ConfigFileSource reads variables from a configuration file, see ReadConfigFile.
+type ConfigFileSource struct {
+	// Path is the path of the file.
+	Path string
+	// Values holds the variables of the file.
+	Values MapSource
+}
+
+// ReadConfigFile reads a configuration file that maps variable names to values. The format is taken from the
+// extension: .json, .yaml, .yml or .toml; any other extension is detected like FormatAuto. Strings are used as they
+// are, and every other value as JSON, so lists and objects can be loaded into slices, maps and structs.
+//
+//	KAFKA_BROKERS: localhost:9092
+//	KAFKA_TOPICS: [umh.v1.enterprise, umh.v1.site]
+//	PORT: 8080
+func ReadConfigFile(path string) (ConfigFileSource, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return ConfigFileSource{}, fmt.Errorf("cannot read configuration file: %w", err)
+	}
+
+	o := newOptions([]Option{WithFormat(configFileFormat(path))})
+	var raw map[string]json.RawMessage
+	if err = decodeValue(string(data), &raw, o); err != nil {
+		return ConfigFileSource{}, fmt.Errorf("configuration file %s: %w", path, err)
+	}
+
+	values := make(MapSource, len(raw))
+	for key, value := range raw {
+		switch {
+		case string(value) == "null":
+			continue
+		case strings.HasPrefix(string(value), `"`):
+			var s string
+			if err = json.Unmarshal(value, &s); err != nil {
+				return ConfigFileSource{}, fmt.Errorf("configuration file %s: %s: %w", path, key, err)
+			}
+			values[key] = s
+		default:
+			values[key] = string(value)
+		}
+	}
+	return ConfigFileSource{Path: path, Values: values}, nil
+}
+
+// Lookup returns the value of key in the file.
+func (c ConfigFileSource) Lookup(key string) (string, bool, error) {
+	return c.Values.Lookup(key)
+}
+
+// Origin reports every variable as OriginConfig, together with the path of the file.
+func (c ConfigFileSource) Origin(string) (Origin, string) {
+	return OriginConfig, c.Path
+}
+
+// configFileFormat returns the format of a configuration file by its extension.
+func configFileFormat(path string) Format {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		return FormatJSON
+	case ".yaml", ".yml":
+		return FormatYAML
+	case ".toml":
+		return FormatTOML
+	default:
+		return FormatAuto
+	}
+}
+
+// flagValue is the flag.Value of the flags defined by RegisterFlags. It keeps the raw text, which is parsed when
+// the variable is loaded.
+type flagValue struct {
+	value  string
+	isBool bool
+}
+
+func (v *flagValue) String() string {
+	if v == nil {
+		return ""
+	}
+	return v.value
+}
+
+func (v *flagValue) Set(s string) error {
+	v.value = s
+	return nil
+}
+
+// IsBoolFlag lets bool fields be set with -name instead of -name=true.
+func (v *flagValue) IsBoolFlag() bool {
+	return v.isBool
+}
+
+// flagNames returns the flag names of the fields of cfg by variable name.
+func flagNames(cfg any) (map[string]string, error) {
+	t := reflect.TypeOf(cfg)
+	if t == nil || t.Kind() != reflect.Pointer || t.Elem().Kind() != reflect.Struct {
+		return nil, fmt.Errorf("env.LoadLayered expects a non-nil pointer to a struct, got %T", cfg)
+	}
+	names := make(map[string]string)
+	err := walkFlags(t.Elem(), func(field reflect.StructField, key, name string) {
+		names[key] = name
+	})
+	return names, err
+}
+
+// walkFlags calls fn with the flag name of every env-tagged field of struct t, recursing into untagged nested
+// structs like Load does. The name is empty for fields tagged `flag:"-"`.
+func walkFlags(t reflect.Type, fn func(field reflect.StructField, key, name string)) error {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		tag, tagged := field.Tag.Lookup(envTag)
+		if !tagged {
+			if field.Type.Kind() == reflect.Struct {
+				if err := walkFlags(field.Type, fn); err != nil {
+					return err
+				}
+			}
+			continue
+		}
+		f, err := parseEnvTag(tag)
+		if err != nil {
+			return fmt.Errorf("field %s: %w", field.Name, err)
+		}
+		name := field.Tag.Get(flagTag)
+		switch name {
+		case "-":
+			name = ""
+		case "":
+			name = FlagName(f.key)
+		}
+		fn(field, f.key, name)
+	}
+	return nil
+}
@@ -0,0 +1,219 @@
+package env
+
+/*
+Copyright 2023 UMH Systems GmbH
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type layersTestConfig struct {
+	Brokers string   `env:"LAYERS_BROKERS" default:"localhost:9092" desc:"Kafka brokers"`
+	Topics  []string `env:"LAYERS_TOPICS" default:"[\"a\"]"`
+	Port    int      `env:"LAYERS_PORT" default:"8080" flag:"port"`
+	Debug   bool     `env:"LAYERS_DEBUG"`
+	Token   string   `env:"LAYERS_TOKEN" flag:"-"`
+}
+
+// TestLoadLayered tests that each layer overrides the ones below it and that the winning layer is reported
+func TestLoadLayered(t *testing.T) {
+	t.Parallel()
+
+	file := filepath.Join(t.TempDir(), "config.yaml")
+	content := "LAYERS_BROKERS: kafka:9092\nLAYERS_TOPICS: [b, c]\nLAYERS_PORT: 9000\nLAYERS_DEBUG: false\nLAYERS_TOKEN: from-file\n"
+	if err := os.WriteFile(file, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	brokersFile := filepath.Join(t.TempDir(), "brokers")
+	if err := os.WriteFile(brokersFile, []byte("secret-kafka:9092\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		layers  Layers
+		args    []string
+		want    layersTestConfig
+		origins map[string]Origin
+	}{
+		{
+			name:    "Case 1: Defaults",
+			layers:  Layers{Env: MapSource{}},
+			want:    layersTestConfig{Brokers: "localhost:9092", Topics: []string{"a"}, Port: 8080},
+			origins: map[string]Origin{"LAYERS_BROKERS": OriginDefault, "LAYERS_PORT": OriginDefault},
+		},
+		{
+			name:    "Case 2: The file overrides defaults",
+			layers:  Layers{Env: MapSource{}, File: file},
+			want:    layersTestConfig{Brokers: "kafka:9092", Topics: []string{"b", "c"}, Port: 9000, Token: "from-file"},
+			origins: map[string]Origin{"LAYERS_BROKERS": OriginConfig, "LAYERS_TOPICS": OriginConfig, "LAYERS_PORT": OriginConfig},
+		},
+		{
+			name:    "Case 3: The environment overrides the file",
+			layers:  Layers{Env: MapSource{"LAYERS_PORT": "9001", "LAYERS_DEBUG": "true"}, File: file},
+			want:    layersTestConfig{Brokers: "kafka:9092", Topics: []string{"b", "c"}, Port: 9001, Debug: true, Token: "from-file"},
+			origins: map[string]Origin{"LAYERS_BROKERS": OriginConfig, "LAYERS_PORT": OriginMap, "LAYERS_DEBUG": OriginMap},
+		},
+		{
+			name:    "Case 4: Flags override the environment",
+			layers:  Layers{Env: MapSource{"LAYERS_PORT": "9001", "LAYERS_DEBUG": "false"}, File: file},
+			args:    []string{"-port", "9002", "-layers-debug", "-layers-topics", `["d"]`},
+			want:    layersTestConfig{Brokers: "kafka:9092", Topics: []string{"d"}, Port: 9002, Debug: true, Token: "from-file"},
+			origins: map[string]Origin{"LAYERS_BROKERS": OriginConfig, "LAYERS_TOPICS": OriginFlag, "LAYERS_PORT": OriginFlag, "LAYERS_DEBUG": OriginFlag},
+		},
+		{
+			name:    "Case 5: Flags that are not set do not count",
+			layers:  Layers{Env: MapSource{"LAYERS_PORT": "9001"}},
+			args:    []string{},
+			want:    layersTestConfig{Brokers: "localhost:9092", Topics: []string{"a"}, Port: 9001},
+			origins: map[string]Origin{"LAYERS_BROKERS": OriginDefault, "LAYERS_PORT": OriginMap},
+		},
+		{
+			name:    "Case 6: Flags override KEY_FILE in the environment",
+			layers:  Layers{Env: MapSource{"LAYERS_BROKERS_FILE": brokersFile}},
+			args:    []string{"-layers-brokers", "flag:9092"},
+			want:    layersTestConfig{Brokers: "flag:9092", Topics: []string{"a"}, Port: 8080},
+			origins: map[string]Origin{"LAYERS_BROKERS": OriginFlag},
+		},
+		{
+			name:    "Case 7: KEY_FILE in the environment overrides the file",
+			layers:  Layers{Env: MapSource{"LAYERS_BROKERS_FILE": brokersFile}, File: file},
+			want:    layersTestConfig{Brokers: "secret-kafka:9092", Topics: []string{"b", "c"}, Port: 9000, Token: "from-file"},
+			origins: map[string]Origin{"LAYERS_BROKERS": OriginFile, "LAYERS_PORT": OriginConfig},
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			var cfg layersTestConfig
+			if tt.args != nil {
+				fs := flag.NewFlagSet("test", flag.ContinueOnError)
+				if err := RegisterFlags(fs, &cfg); err != nil {
+					t.Fatalf("RegisterFlags() error = %v", err)
+				}
+				if err := fs.Parse(tt.args); err != nil {
+					t.Fatalf("Parse() error = %v", err)
+				}
+				tt.layers.Flags = fs
+			}
+			snapshot, err := LoadLayered(&cfg, tt.layers)
+			if err != nil {
+				t.Fatalf("LoadLayered() error = %v", err)
+			}
+			if !reflect.DeepEqual(cfg, tt.want) {
+				t.Errorf("LoadLayered() = %+v, want %+v", cfg, tt.want)
+			}
+			for _, res := range snapshot {
+				if want, ok := tt.origins[res.Key]; ok && res.Origin != want {
+					t.Errorf("origin of %s = %s, want %s", res.Key, res.Origin, want)
+				}
+			}
+		})
+	}
+}
+
+// TestRegisterFlags tests the flags defined for a struct
+func TestRegisterFlags(t *testing.T) {
+	t.Parallel()
+
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	fs.String("layers-brokers", "mine", "defined by the application")
+	if err := RegisterFlags(fs, &layersTestConfig{}); err != nil {
+		t.Fatalf("RegisterFlags() error = %v", err)
+	}
+	if f := fs.Lookup("layers-brokers"); f.Usage != "defined by the application" {
+		t.Errorf("existing flag was replaced: %+v", f)
+	}
+	if f := fs.Lookup("port"); f == nil || f.DefValue != "8080" || f.Usage != "sets LAYERS_PORT" {
+		t.Errorf("flag port = %+v", f)
+	}
+	if fs.Lookup("layers-token") != nil {
+		t.Error(`flag:"-" defined a flag`)
+	}
+	if err := fs.Parse([]string{"-layers-token", "x"}); err == nil {
+		t.Error("Parse() accepted an undefined flag")
+	}
+
+	var buf bytes.Buffer
+	fs.SetOutput(&buf)
+	fs.PrintDefaults()
+	if !strings.Contains(buf.String(), "-port value\n    \tsets LAYERS_PORT (default 8080)") {
+		t.Errorf("PrintDefaults() =\n%s", buf.String())
+	}
+
+	if err := RegisterFlags(fs, layersTestConfig{}); err == nil {
+		t.Error("RegisterFlags() error = nil for a struct value")
+	}
+}
+
+// TestReadConfigFile tests the formats of configuration files
+func TestReadConfigFile(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	tests := []struct {
+		name    string
+		file    string
+		content string
+		want    MapSource
+		wantErr bool
+		// wantSyntax expects a *SyntaxError.
+		wantSyntax bool
+	}{
+		{name: "Case 1: JSON", file: "config.json", content: `{"PORT": 8080, "TOPICS": ["a"], "NAME": "umh", "UNSET": null}`, want: MapSource{"PORT": "8080", "TOPICS": `["a"]`, "NAME": "umh"}},
+		{name: "Case 2: YAML", file: "config.yml", content: "PORT: 8080\nTOPICS: [a]\nNAME: umh\nDEBUG: true\n", want: MapSource{"PORT": "8080", "TOPICS": `["a"]`, "NAME": "umh", "DEBUG": "true"}},
+		{name: "Case 3: TOML", file: "config.toml", content: "PORT = 8080\nTOPICS = [\"a\"]\nNAME = \"umh\"\n", want: MapSource{"PORT": "8080", "TOPICS": `["a"]`, "NAME": "umh"}},
+		{name: "Case 4: Detected format", file: "config", content: "NAME = \"umh\"\n", want: MapSource{"NAME": "umh"}},
+		{name: "Case 5: Syntax error", file: "broken.yaml", content: "NAME: umh\n  PORT: 1\n", wantErr: true, wantSyntax: true},
+		{name: "Case 6: Not an object", file: "list.json", content: `["a"]`, wantErr: true},
+		{name: "Case 7: Missing file", file: "missing.json", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			path := filepath.Join(dir, tt.file)
+			if tt.content != "" {
+				if err := os.WriteFile(path, []byte(tt.content), 0o600); err != nil {
+					t.Fatal(err)
+				}
+			}
+			got, err := ReadConfigFile(path)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ReadConfigFile() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got.Values, tt.want) {
+				t.Errorf("ReadConfigFile() = %v, want %v", got.Values, tt.want)
+			}
+			var syntaxErr *SyntaxError
+			if errors.As(err, &syntaxErr) != tt.wantSyntax {
+				t.Errorf("ReadConfigFile() error = %v, wantSyntax %v", err, tt.wantSyntax)
+			}
+		})
+	}
+}
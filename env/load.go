@@ -0,0 +1,267 @@
+package env
+
+/*
+Copyright 2023 UMH Systems GmbH
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/united-manufacturing-hub/umh-utils/parse"
+)
+
+const (
+	// envTag holds the name of the environment variable and its flags, e.g. `env:"KAFKA_BROKERS,required"`.
+	envTag = "env"
+	// defaultTag holds the fallback value used when the environment variable is not set, e.g. `default:"localhost:9092"`.
+	defaultTag = "default"
+)
+
+// Load fills the struct pointed to by cfg from environment variables, as described by its struct tags.
+//
+// Every exported field with an `env:"KEY"` tag is read from the environment variable KEY. Adding ",required" to the
+// tag makes the variable mandatory. The `default:"..."` tag provides the fallback value for variables that are not
+// set; without it, the current value of the field is kept. Nested structs without an env tag are loaded recursively.
+//
+// Fields of type string, int, uint64, float64, bool, time.Duration and time.Time follow the rules of GetAsString,
+// GetAsInt, GetAsUint64, GetAsFloat64, GetAsBool, GetAsDuration and GetAsTime. Slices and maps are split like
+// GetAsSlice and GetAsMap do, e.g. `default:"a,b"`, and still accept a JSON array or object; the ",sep=;" and
+// ",kvsep=:" flags set their separators, like the Separator and KeyValueSeparator options. Every other type is
+// parsed like GetAs does, so registered parsers and encoding.TextUnmarshaler are used before falling back to JSON.
+// The ",sensitive" flag redacts the value from error messages, ",quantity" parses an int field with parse.Quantity, like
+// GetAsQuantity, ",emptyasunset" treats empty values as not set, like the EmptyAsUnset option, and ",strictbool"
+// accepts only the boolean grammar, like the StrictBool option.
+//
+// The `validate:"..."` tag adds validation rules, separated by commas: min=N, max=N, oneof=A B C, nonempty, url,
+// hostport, file and regex=PATTERN, which must come last. They match the Min, Max, OneOf, NonEmpty, URL, HostPort,
+// FileExists and Regex options. The `desc:"..."` tag documents the variable, like the Description option, and
+// `deprecated:"OLD_NAME,OLDER_NAME"` lists former names that are still read, like the Deprecated option.
+//
+// All fields are loaded even if some of them fail; the errors are returned together as Errors.
+func Load(cfg any, opts ...Option) error {
+	return defaultReader.Load(cfg, opts...)
+}
+
+// load fills cfg using l and returns the collected errors.
+func load(l *Loader, cfg any) error {
+	v := reflect.ValueOf(cfg)
+	if v.Kind() != reflect.Pointer || v.IsNil() || v.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("env.Load expects a non-nil pointer to a struct, got %T", cfg)
+	}
+	if err := loadStruct(v.Elem(), l); err != nil {
+		return err
+	}
+	return l.Err()
+}
+
+// loadStruct loads every tagged field of v into l and recurses into untagged nested structs. It only returns an
+// error for invalid tags; lookup errors are collected by l.
+func loadStruct(v reflect.Value, l *Loader) error {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+
+		tag, tagged := field.Tag.Lookup(envTag)
+		if !tagged {
+			if field.Type.Kind() == reflect.Struct {
+				if err := loadStruct(v.Field(i), l); err != nil {
+					return err
+				}
+			}
+			continue
+		}
+
+		f, err := parseEnvTag(tag)
+		if err != nil {
+			return fmt.Errorf("field %s: %w", field.Name, err)
+		}
+		if f.quantity && field.Type.Kind() != reflect.Int {
+			return fmt.Errorf("field %s: the quantity option requires an int field, got %s", field.Name, field.Type)
+		}
+		if rules, ok := field.Tag.Lookup(validateTag); ok {
+			validators, err := parseValidateTag(rules)
+			if err != nil {
+				return fmt.Errorf("field %s: %w", field.Name, err)
+			}
+			f.opts = append(f.opts, validators...)
+		}
+		if names, ok := field.Tag.Lookup(deprecatedTag); ok {
+			f.opts = append(f.opts, Deprecated(strings.Split(names, ",")...))
+		}
+		if desc, ok := field.Tag.Lookup(descTag); ok {
+			f.opts = append(f.opts, Description(desc))
+		}
+
+		fallback := reflect.New(field.Type).Elem()
+		fallback.Set(v.Field(i))
+		if def, ok := field.Tag.Lookup(defaultTag); ok {
+			if err = f.parseDefault(fallback, def, l.reader.options(l.with(f.opts))); err != nil {
+				return fmt.Errorf("field %s: invalid default value %q: %w", field.Name, def, err)
+			}
+		}
+
+		loadField(l, v.Field(i), f, fallback)
+	}
+	return nil
+}
+
+// envField is a struct field as described by its env tag.
+type envField struct {
+	key  string
+	opts []Option
+	// required makes the variable mandatory.
+	required bool
+	// quantity parses the value with parse.Quantity.
+	quantity bool
+}
+
+// parseEnvTag splits an env tag into the variable name and its flags.
+func parseEnvTag(tag string) (envField, error) {
+	parts := strings.Split(tag, ",")
+	f := envField{key: strings.TrimSpace(parts[0])}
+	if f.key == "" {
+		return envField{}, errors.New("env tag has no variable name")
+	}
+	for _, flag := range parts[1:] {
+		// The separators are taken as they are, so that e.g. "sep= " splits on spaces
+		if name, sep, ok := strings.Cut(flag, "="); ok {
+			switch strings.TrimSpace(name) {
+			case "sep":
+				f.opts = append(f.opts, Separator(sep))
+				continue
+			case "kvsep":
+				f.opts = append(f.opts, KeyValueSeparator(sep))
+				continue
+			}
+		}
+		switch strings.TrimSpace(flag) {
+		case "required":
+			f.required = true
+		case "sensitive":
+			f.opts = append(f.opts, Sensitive())
+		case "quantity":
+			f.quantity = true
+		case "emptyasunset":
+			f.opts = append(f.opts, EmptyAsUnset())
+		case "strictbool":
+			f.opts = append(f.opts, StrictBool())
+		default:
+			return envField{}, fmt.Errorf("unknown env tag option %q", flag)
+		}
+	}
+	return f, nil
+}
+
+// parseDefault parses the value of the default tag into dst.
+func (f envField) parseDefault(dst reflect.Value, def string, o *options) error {
+	if f.quantity {
+		i, err := parse.Quantity(def)
+		if err != nil {
+			return err
+		}
+		dst.SetInt(int64(i))
+		return nil
+	}
+	return parseField(dst, def, o)
+}
+
+// parseField parses raw into the field dst. Lists and maps are split into their elements like GetAsSlice and GetAsMap
+// do, unless their type has a parser of its own or raw is a JSON array or object, as configuration files write them.
+func parseField(dst reflect.Value, raw string, o *options) error {
+	t := dst.Type()
+	if !splitsElements(t) {
+		return parseValue(dst, raw, o)
+	}
+
+	var v reflect.Value
+	var err error
+	switch trimmed := strings.TrimSpace(raw); {
+	case t.Kind() == reflect.Slice && strings.HasPrefix(trimmed, "["), t.Kind() == reflect.Map && strings.HasPrefix(trimmed, "{"):
+		return parseValue(dst, raw, o)
+	case t.Kind() == reflect.Slice:
+		v, err = parseList(t, raw, o)
+	default:
+		v, err = parseMap(t, raw, o)
+	}
+	if err != nil {
+		return err
+	}
+	dst.Set(v)
+	return nil
+}
+
+// splitsElements reports whether fields of type t are split into elements: lists other than []byte, and maps, that
+// have no parser of their own.
+func splitsElements(t reflect.Type) bool {
+	switch {
+	case t.Kind() == reflect.Slice && t.Elem().Kind() != reflect.Uint8, t.Kind() == reflect.Map:
+		return textParser(t) == nil
+	default:
+		return false
+	}
+}
+
+// loadField sets dst from the variable described by f using the Loader method matching its type.
+func loadField(l *Loader, dst reflect.Value, f envField, fallback reflect.Value) {
+	key, required, opts := f.key, f.required, f.opts
+	if f.quantity {
+		dst.SetInt(int64(l.Quantity(key, required, int(fallback.Int()), opts...)))
+		return
+	}
+
+	// Only the predeclared types themselves; named types go through getAsValue, so that their registered parser or
+	// UnmarshalText is used
+	switch dst.Type() {
+	case durationType:
+		dst.SetInt(int64(l.Duration(key, required, time.Duration(fallback.Int()), opts...)))
+	case timeType:
+		var t time.Time
+		reflect.ValueOf(&t).Elem().Set(fallback)
+		dst.Set(reflect.ValueOf(l.Time(key, required, t, opts...)))
+	case kindTypes[reflect.String]:
+		dst.SetString(l.String(key, required, fallback.String(), opts...))
+	case kindTypes[reflect.Int]:
+		dst.SetInt(int64(l.Int(key, required, int(fallback.Int()), opts...)))
+	case kindTypes[reflect.Uint64]:
+		dst.SetUint(l.Uint64(key, required, fallback.Uint(), opts...))
+	case kindTypes[reflect.Float64]:
+		dst.SetFloat(l.Float64(key, required, fallback.Float(), opts...))
+	case kindTypes[reflect.Bool]:
+		dst.SetBool(l.Bool(key, required, fallback.Bool(), opts...))
+	default:
+		if splitsElements(dst.Type()) {
+			opts = append(opts[:len(opts):len(opts)], splitting())
+		}
+		l.Add(getAsValue(l.reader, dst, key, required, fallback, l.with(opts)))
+	}
+}
+
+// getAsValue is the reflection counterpart of GetAs: it is get with the value of dst, so that fields follow the same
+// rules as the getters.
+func getAsValue(r *Reader, dst reflect.Value, key string, required bool, fallback reflect.Value, opts []Option) error {
+	value, err := get(r, key, required, fallback, opts, func(raw string, o *options) (reflect.Value, error) {
+		target := reflect.New(dst.Type()).Elem()
+		return target, parseField(target, raw, o)
+	})
+	dst.Set(value)
+	return err
+}
@@ -0,0 +1,231 @@
+package env
+
+/*
+Copyright 2023 UMH Systems GmbH
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type loadKafkaConfig struct {
+	Brokers string   `env:"LOAD_KAFKA_BROKERS,required"`
+	Topics  []string `env:"LOAD_KAFKA_TOPICS" default:"umh.v1"`
+}
+
+type loadTestConfig struct {
+	Name   string  `env:"LOAD_NAME" default:"umh"`
+	Port   int     `env:"LOAD_PORT" default:"8080"`
+	Buffer uint64  `env:"LOAD_BUFFER"`
+	Ratio  float64 `env:"LOAD_RATIO" default:"0.5"`
+	Debug  bool    `env:"LOAD_DEBUG"`
+	Kafka  loadKafkaConfig
+}
+
+// loadTestMode is a named string type that validates itself.
+type loadTestMode string
+
+func (m *loadTestMode) UnmarshalText(text []byte) error {
+	switch mode := strings.ToLower(string(text)); mode {
+	case "auto", "manual":
+		*m = loadTestMode(mode)
+		return nil
+	default:
+		return fmt.Errorf("unknown mode %q", text)
+	}
+}
+
+// loadTestPort is a named int type without methods of its own.
+type loadTestPort int
+
+// TestLoad tests the Load function
+func TestLoad(t *testing.T) {
+	tests := []struct {
+		name    string
+		env     map[string]string
+		initial loadTestConfig
+		want    loadTestConfig
+		wantErr bool
+	}{
+		{
+			name: "Case 1: All variables set, return values",
+			env: map[string]string{
+				"LOAD_NAME":          "factory",
+				"LOAD_PORT":          "9090",
+				"LOAD_BUFFER":        "1024",
+				"LOAD_RATIO":         "0.25",
+				"LOAD_DEBUG":         "true",
+				"LOAD_KAFKA_BROKERS": "kafka:9092",
+				"LOAD_KAFKA_TOPICS":  `["a","b"]`,
+			},
+			want: loadTestConfig{
+				Name:   "factory",
+				Port:   9090,
+				Buffer: 1024,
+				Ratio:  0.25,
+				Debug:  true,
+				Kafka:  loadKafkaConfig{Brokers: "kafka:9092", Topics: []string{"a", "b"}},
+			},
+		},
+		{
+			name: "Case 2: Optional variables not set, return defaults and keep initial values",
+			env: map[string]string{
+				"LOAD_KAFKA_BROKERS": "kafka:9092",
+			},
+			initial: loadTestConfig{Buffer: 42},
+			want: loadTestConfig{
+				Name:   "umh",
+				Port:   8080,
+				Buffer: 42,
+				Ratio:  0.5,
+				Kafka:  loadKafkaConfig{Brokers: "kafka:9092", Topics: []string{"umh.v1"}},
+			},
+		},
+		{
+			name:    "Case 3: Required nested variable not set, return error",
+			env:     map[string]string{},
+			wantErr: true,
+		},
+		{
+			name: "Case 4: Variable is not an integer, return error",
+			env: map[string]string{
+				"LOAD_PORT":          "wrong",
+				"LOAD_KAFKA_BROKERS": "kafka:9092",
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for key, value := range tt.env {
+				t.Setenv(key, value)
+			}
+			got := tt.initial
+			err := Load(&got)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Load() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Load() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestLoadInvalidTarget tests that Load rejects targets that are not struct pointers
+func TestLoadInvalidTarget(t *testing.T) {
+	var cfg loadTestConfig
+	for _, target := range []any{nil, cfg, new(int)} {
+		if err := Load(target); err == nil {
+			t.Errorf("Load(%T) expected error", target)
+		}
+	}
+}
+
+// TestLoadNamedTypes tests that named types of basic kinds use their UnmarshalText
+func TestLoadNamedTypes(t *testing.T) {
+	t.Parallel()
+
+	type config struct {
+		Mode loadTestMode `env:"MODE" default:"auto"`
+		Port loadTestPort `env:"PORT"`
+	}
+	tests := []struct {
+		source  MapSource
+		name    string
+		want    config
+		wantErr bool
+	}{
+		{name: "Case 1: UnmarshalText is used", source: MapSource{"MODE": "MANUAL", "PORT": "1883"}, want: config{Mode: "manual", Port: 1883}},
+		{name: "Case 2: Default goes through UnmarshalText too", source: MapSource{}, want: config{Mode: "auto"}},
+		{name: "Case 3: UnmarshalText rejects the value", source: MapSource{"MODE": "BOGUS"}, want: config{Mode: "auto"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			var got config
+			err := NewReader(tt.source, WithRegistry(NewRegistry())).Load(&got)
+			var parseErr *ParseError
+			if tt.wantErr != errors.As(err, &parseErr) {
+				t.Fatalf("Load() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("Load() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestLoadCollections tests that list and map fields are split into their elements
+func TestLoadCollections(t *testing.T) {
+	t.Parallel()
+
+	type config struct {
+		Topics  []string          `env:"TOPICS" default:"a,b"`
+		Ports   []int             `env:"PORTS,sep=;"`
+		Labels  map[string]string `env:"LABELS,sep=;,kvsep=:"`
+		Weights map[string]int    `env:"WEIGHTS"`
+		Raw     []byte            `env:"RAW"`
+	}
+	tests := []struct {
+		source  MapSource
+		name    string
+		want    config
+		wantErr bool
+	}{
+		{name: "Case 1: Defaults are split", source: MapSource{}, want: config{Topics: []string{"a", "b"}}},
+		{
+			name:   "Case 2: Values are split on the separators of the tag",
+			source: MapSource{"TOPICS": "umh.v1, umh.v2", "PORTS": "1883;8883", "LABELS": "site:aachen; line:1", "WEIGHTS": "a=1,b=2"},
+			want: config{
+				Topics:  []string{"umh.v1", "umh.v2"},
+				Ports:   []int{1883, 8883},
+				Labels:  map[string]string{"site": "aachen", "line": "1"},
+				Weights: map[string]int{"a": 1, "b": 2},
+			},
+		},
+		{
+			name:   "Case 3: JSON arrays and objects are still accepted",
+			source: MapSource{"TOPICS": `["x,y"]`, "WEIGHTS": `{"a":1}`},
+			want:   config{Topics: []string{"x,y"}, Weights: map[string]int{"a": 1}},
+		},
+		{name: "Case 4: []byte is not split", source: MapSource{"RAW": `"aGk="`}, want: config{Topics: []string{"a", "b"}, Raw: []byte("hi")}},
+		{name: "Case 5: Invalid elements are reported", source: MapSource{"PORTS": "1883;mqtt"}, want: config{Topics: []string{"a", "b"}}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			var got config
+			err := NewReader(tt.source, WithRegistry(NewRegistry())).Load(&got)
+			var elementErr *ElementError
+			if tt.wantErr != errors.As(err, &elementErr) {
+				t.Fatalf("Load() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Load() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
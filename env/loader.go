@@ -0,0 +1,128 @@
+package env
+
+/*
+Copyright 2023 UMH Systems GmbH
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+import (
+	"errors"
+	"time"
+)
+
+// Loader runs a series of lookups and collects their errors, so that every missing or malformed variable is
+// reported at once instead of failing on the first one.
+//
+//	l := env.NewLoader()
+//	brokers := l.String("KAFKA_BROKERS", true, "")
+//	port := l.Int("PORT", false, 8080)
+//	l.Add(env.GetAsType("TOPICS", &topics, false, nil))
+//	if err := l.Err(); err != nil {
+//		return err
+//	}
+type Loader struct {
+	reader *Reader
+	opts   []Option
+	errs   Errors
+}
+
+// NewLoader returns a Loader that reads from the environment of the process and applies opts to every lookup.
+func NewLoader(opts ...Option) *Loader {
+	return defaultReader.NewLoader(opts...)
+}
+
+// String is GetAsString, with the error collected by the Loader.
+func (l *Loader) String(key string, required bool, fallback string, opts ...Option) string {
+	value, err := l.reader.GetAsString(key, required, fallback, l.with(opts)...)
+	l.Add(err)
+	return value
+}
+
+// Int is GetAsInt, with the error collected by the Loader.
+func (l *Loader) Int(key string, required bool, fallback int, opts ...Option) int {
+	value, err := l.reader.GetAsInt(key, required, fallback, l.with(opts)...)
+	l.Add(err)
+	return value
+}
+
+// Uint64 is GetAsUint64, with the error collected by the Loader.
+func (l *Loader) Uint64(key string, required bool, fallback uint64, opts ...Option) uint64 {
+	value, err := l.reader.GetAsUint64(key, required, fallback, l.with(opts)...)
+	l.Add(err)
+	return value
+}
+
+// Float64 is GetAsFloat64, with the error collected by the Loader.
+func (l *Loader) Float64(key string, required bool, fallback float64, opts ...Option) float64 {
+	value, err := l.reader.GetAsFloat64(key, required, fallback, l.with(opts)...)
+	l.Add(err)
+	return value
+}
+
+// Bool is GetAsBool, with the error collected by the Loader.
+func (l *Loader) Bool(key string, required bool, fallback bool, opts ...Option) bool {
+	value, err := l.reader.GetAsBool(key, required, fallback, l.with(opts)...)
+	l.Add(err)
+	return value
+}
+
+// Duration is GetAsDuration, with the error collected by the Loader.
+func (l *Loader) Duration(key string, required bool, fallback time.Duration, opts ...Option) time.Duration {
+	value, err := l.reader.GetAsDuration(key, required, fallback, l.with(opts)...)
+	l.Add(err)
+	return value
+}
+
+// Time is GetAsTime, with the error collected by the Loader.
+func (l *Loader) Time(key string, required bool, fallback time.Time, opts ...Option) time.Time {
+	value, err := l.reader.GetAsTime(key, required, fallback, l.with(opts)...)
+	l.Add(err)
+	return value
+}
+
+// Quantity is GetAsQuantity, with the error collected by the Loader.
+func (l *Loader) Quantity(key string, required bool, fallback int, opts ...Option) int {
+	value, err := l.reader.GetAsQuantity(key, required, fallback, l.with(opts)...)
+	l.Add(err)
+	return value
+}
+
+// Add collects err, if it is not nil. Errors returned by other lookups, such as GetAsType, can be added this way.
+func (l *Loader) Add(err error) {
+	if err == nil {
+		return
+	}
+	var errs Errors
+	if errors.As(err, &errs) {
+		l.errs = append(l.errs, errs...)
+		return
+	}
+	l.errs = append(l.errs, err)
+}
+
+// Err returns the collected errors as Errors, or nil if every lookup succeeded.
+func (l *Loader) Err() error {
+	if len(l.errs) == 0 {
+		return nil
+	}
+	return l.errs
+}
+
+// with returns the Loader options followed by opts.
+func (l *Loader) with(opts []Option) []Option {
+	if len(opts) == 0 {
+		return l.opts
+	}
+	return append(append([]Option{}, l.opts...), opts...)
+}
@@ -0,0 +1,149 @@
+package env
+
+/*
+Copyright 2023 UMH Systems GmbH
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestLoader tests that the Loader collects the errors of every lookup
+func TestLoader(t *testing.T) {
+	t.Setenv("LOADER_PORT", "wrong")
+	t.Setenv("LOADER_PASSWORD_LENGTH", "hunter2")
+	t.Setenv("LOADER_DEBUG", "true")
+
+	l := NewLoader()
+	brokers := l.String("LOADER_BROKERS", true, "")
+	port := l.Int("LOADER_PORT", false, 8080)
+	length := l.Uint64("LOADER_PASSWORD_LENGTH", false, 12, Sensitive())
+	ratio := l.Float64("LOADER_RATIO", false, 0.5)
+	debug := l.Bool("LOADER_DEBUG", false, false)
+	var topics []string
+	l.Add(GetAsType("LOADER_TOPICS", &topics, true, nil))
+
+	if brokers != "" || port != 8080 || length != 12 || ratio != 0.5 || !debug {
+		t.Errorf("Loader returned unexpected values: %q %d %d %v %v", brokers, port, length, ratio, debug)
+	}
+
+	err := l.Err()
+	var errs Errors
+	if !errors.As(err, &errs) {
+		t.Fatalf("Loader.Err() = %v, want Errors", err)
+	}
+	if len(errs) != 4 {
+		t.Fatalf("Loader.Err() collected %d errors, want 4: %v", len(errs), err)
+	}
+
+	msg := err.Error()
+	for _, want := range []string{
+		"4 environment variable errors",
+		"LOADER_BROKERS is required but not set (expected string)",
+		`LOADER_PORT has value "wrong", which is not a valid int`,
+		`LOADER_PASSWORD_LENGTH has value "[REDACTED]", which is not a valid uint64`,
+		"LOADER_TOPICS is required but not set (expected []string)",
+	} {
+		if !strings.Contains(msg, want) {
+			t.Errorf("Loader.Err() = %q, want it to contain %q", msg, want)
+		}
+	}
+	if strings.Contains(msg, "hunter2") {
+		t.Errorf("Loader.Err() = %q, leaks a sensitive value", msg)
+	}
+}
+
+// TestLoaderNoErrors tests that Err returns nil when every lookup succeeds
+func TestLoaderNoErrors(t *testing.T) {
+	l := NewLoader()
+	_ = l.String("LOADER_NONEXISTENT_VAR", false, "fallback")
+	l.Add(nil)
+	if err := l.Err(); err != nil {
+		t.Errorf("Loader.Err() = %v, want nil", err)
+	}
+}
+
+// TestLoadCollectsErrors tests that Load reports every failing field
+func TestLoadCollectsErrors(t *testing.T) {
+	t.Setenv("LOAD_PORT", "wrong")
+	t.Setenv("LOAD_RATIO", "wrong")
+
+	var cfg loadTestConfig
+	err := Load(&cfg)
+	var errs Errors
+	if !errors.As(err, &errs) || len(errs) != 3 {
+		t.Errorf("Load() error = %v, want 3 collected errors", err)
+	}
+}
+
+// TestEmptyAsUnset tests that empty values fall back or fail as if unset, per call, per Loader and per field
+func TestEmptyAsUnset(t *testing.T) {
+	t.Parallel()
+
+	password := filepath.Join(t.TempDir(), "password")
+	if err := os.WriteFile(password, []byte("s3cr3t\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	r := NewReader(MapSource{
+		"EMPTY_PORT":          "",
+		"EMPTY_DEBUG":         "  ",
+		"EMPTY_CA":            "",
+		"EMPTY_NAME":          "umh",
+		"EMPTY_PASSWORD":      "",
+		"EMPTY_PASSWORD_FILE": password,
+		"EMPTY_TOKEN":         "abc",
+		"EMPTY_TOKEN_FILE":    "",
+	})
+
+	if got, err := r.GetAsInt("EMPTY_PORT", false, 8080); err == nil || got != 8080 {
+		t.Errorf("GetAsInt() = %d, %v, want a parse error without EmptyAsUnset", got, err)
+	}
+	if got, err := r.GetAsInt("EMPTY_PORT", false, 8080, EmptyAsUnset()); err != nil || got != 8080 {
+		t.Errorf("GetAsInt() = %d, %v, want fallback", got, err)
+	}
+	if _, err := r.GetAsBool("EMPTY_DEBUG", true, false, EmptyAsUnset()); !errors.Is(err, ErrNotSet) {
+		t.Errorf("GetAsBool() error = %v, want ErrNotSet", err)
+	}
+	// An empty KEY next to KEY_FILE, as Helm charts render them, is not a conflict
+	if _, err := r.GetAsString("EMPTY_PASSWORD", true, ""); !errors.Is(err, ErrFileConflict) {
+		t.Errorf("GetAsString() error = %v, want ErrFileConflict without EmptyAsUnset", err)
+	}
+	if got, err := r.GetAsString("EMPTY_PASSWORD", true, "", EmptyAsUnset()); err != nil || got != "s3cr3t" {
+		t.Errorf("GetAsString() = %q, %v, want the content of the file", got, err)
+	}
+	if got, err := r.GetAsString("EMPTY_TOKEN", true, "", EmptyAsUnset()); err != nil || got != "abc" {
+		t.Errorf("GetAsString() = %q, %v, want the value next to an empty KEY_FILE", got, err)
+	}
+
+	l := r.NewLoader(EmptyAsUnset())
+	port := l.Int("EMPTY_PORT", false, 8080)
+	debug := l.Bool("EMPTY_DEBUG", false, true)
+	name := l.String("EMPTY_NAME", true, "")
+	if err := l.Err(); err != nil || port != 8080 || !debug || name != "umh" {
+		t.Errorf("Loader = %d, %v, %q, %v, want fallbacks and the set value", port, debug, name, err)
+	}
+
+	var cfg struct {
+		CA   string `env:"EMPTY_CA,emptyasunset" default:"/etc/ssl/ca.pem"`
+		Port int    `env:"EMPTY_PORT,emptyasunset" default:"9092"`
+	}
+	if err := r.Load(&cfg); err != nil || cfg.CA != "/etc/ssl/ca.pem" || cfg.Port != 9092 {
+		t.Errorf("Load() = %+v, %v, want defaults", cfg, err)
+	}
+}
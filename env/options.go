@@ -0,0 +1,141 @@
+package env
+
+/*
+Copyright 2023 UMH Systems GmbH
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+import (
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// Option changes how a single lookup is performed.
+type Option func(*options)
+
+// options holds the settings collected from a list of Option values.
+type options struct {
+	// sensitive hides the raw value in error messages.
+	sensitive bool
+	// unit is the unit of durations given as a bare number.
+	unit time.Duration
+	// separator splits the elements of lists and maps.
+	separator string
+	// keyValueSeparator splits the key from the value of map elements.
+	keyValueSeparator string
+	// split is set by the lookups that split lists and maps into elements, so that their values are formatted that
+	// way, see formatValue.
+	split bool
+	// validators are run against every successfully parsed value.
+	validators []validator
+	// description documents the variable in the registry.
+	description string
+	// registry records the variable; nil means DefaultRegistry.
+	registry *Registry
+	// emptyAsUnset treats empty and whitespace-only values as not set.
+	emptyAsUnset bool
+	// aliases are looked up in order if the variable is not set.
+	aliases []Alias
+	// format is the syntax of structured values.
+	format Format
+	// merge decodes JSON values onto a copy of the fallback.
+	merge bool
+	// disallowUnknownFields rejects JSON fields the target does not have.
+	disallowUnknownFields bool
+	// strictBool accepts only the words of the boolean grammar.
+	strictBool bool
+	// trueWords and falseWords replace the words of the boolean grammar if set.
+	trueWords, falseWords []string
+	// policy decides how malformed values are handled.
+	policy Policy
+	// logger receives warnings; nil means the global logger of zap.
+	logger *zap.SugaredLogger
+}
+
+// newOptions applies opts in order and returns the resulting settings.
+func newOptions(opts []Option) *options {
+	o := &options{unit: time.Second, separator: ",", keyValueSeparator: "=", format: FormatJSON, policy: DefaultPolicy()}
+	for _, opt := range opts {
+		if opt != nil {
+			opt(o)
+		}
+	}
+	return o
+}
+
+// Sensitive marks the variable as a secret, so its value is redacted from error messages.
+func Sensitive() Option {
+	return func(o *options) {
+		o.sensitive = true
+	}
+}
+
+// Unit sets the unit of durations given as a bare number, such as "30". The default is time.Second.
+func Unit(unit time.Duration) Option {
+	return func(o *options) {
+		o.unit = unit
+	}
+}
+
+// Separator sets the delimiter between the elements of lists and maps. The default is ",".
+func Separator(sep string) Option {
+	return func(o *options) {
+		o.separator = sep
+	}
+}
+
+// KeyValueSeparator sets the delimiter between the key and the value of map elements. The default, also used for
+// an empty sep, is "=".
+func KeyValueSeparator(sep string) Option {
+	return func(o *options) {
+		o.keyValueSeparator = sep
+	}
+}
+
+// splitting marks a lookup that splits lists and maps into elements.
+func splitting() Option {
+	return func(o *options) {
+		o.split = true
+	}
+}
+
+// EmptyAsUnset treats variables that are set to an empty or whitespace-only string as not set, so that the fallback
+// value is used, or a *NotSetError is returned for required variables. This suits charts that render optional
+// values as "". It applies to KEY_FILE as well, so an empty KEY next to KEY_FILE reads the file.
+func EmptyAsUnset() Option {
+	return func(o *options) {
+		o.emptyAsUnset = true
+	}
+}
+
+// isEmpty reports whether value must be treated as not set.
+func (o *options) isEmpty(value string) bool {
+	return o.emptyAsUnset && strings.TrimSpace(value) == ""
+}
+
+// Description documents the variable. It is recorded in the Registry, from which the documentation is generated.
+func Description(text string) Option {
+	return func(o *options) {
+		o.description = text
+	}
+}
+
+// WithRegistry records the variable in reg instead of DefaultRegistry.
+func WithRegistry(reg *Registry) Option {
+	return func(o *options) {
+		o.registry = reg
+	}
+}
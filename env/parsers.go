@@ -0,0 +1,330 @@
+package env
+
+/*
+Copyright 2023 UMH Systems GmbH
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+import (
+	"encoding"
+	"errors"
+	"math"
+	"net"
+	"net/netip"
+	"net/url"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	durationType = reflect.TypeOf(time.Duration(0))
+	timeType     = reflect.TypeOf(time.Time{})
+)
+
+// unixMilliThreshold separates Unix seconds from Unix milliseconds. As seconds, it would be a date in the year
+// 5138; as milliseconds, it is in 1973.
+const unixMilliThreshold = 100_000_000_000
+
+// parseDuration parses Go duration syntax, such as "1m30s", or a bare number in the given unit.
+func parseDuration(value string, unit time.Duration) (time.Duration, error) {
+	value = strings.TrimSpace(value)
+	if f, err := strconv.ParseFloat(value, 64); err == nil {
+		d := f * float64(unit)
+		// float64(math.MaxInt64) rounds up to 2^63, which does not fit
+		if math.IsNaN(d) || d >= math.MaxInt64 || d < math.MinInt64 {
+			return 0, errors.New("duration out of range")
+		}
+		return time.Duration(d), nil
+	}
+	return time.ParseDuration(value)
+}
+
+// parseTime parses an RFC 3339 timestamp, or an integer holding Unix seconds or Unix milliseconds. Integers with
+// an absolute value of at least 10^11 are taken as milliseconds.
+func parseTime(value string) (time.Time, error) {
+	value = strings.TrimSpace(value)
+	if n, err := strconv.ParseInt(value, 10, 64); err == nil {
+		if n >= unixMilliThreshold || n <= -unixMilliThreshold {
+			return time.UnixMilli(n).UTC(), nil
+		}
+		return time.Unix(n, 0).UTC(), nil
+	}
+	t, err := time.Parse(time.RFC3339Nano, value)
+	if err != nil {
+		return time.Time{}, errors.New("expected an RFC 3339 timestamp or a Unix timestamp in seconds or milliseconds")
+	}
+	return t, nil
+}
+
+// parseFunc parses a raw value into a value of the type it is registered for.
+type parseFunc func(raw string, o *options) (any, error)
+
+var (
+	// parsers holds the parser of every type with built-in or registered support, see RegisterParser.
+	parsers   = builtinParsers()
+	parsersMu sync.RWMutex
+
+	// kindTypes maps the basic kinds to their predeclared type, whose parser is used for named types of that kind.
+	kindTypes = map[reflect.Kind]reflect.Type{
+		reflect.String:  reflect.TypeOf(""),
+		reflect.Bool:    reflect.TypeOf(false),
+		reflect.Int:     reflect.TypeOf(int(0)),
+		reflect.Int8:    reflect.TypeOf(int8(0)),
+		reflect.Int16:   reflect.TypeOf(int16(0)),
+		reflect.Int32:   reflect.TypeOf(int32(0)),
+		reflect.Int64:   reflect.TypeOf(int64(0)),
+		reflect.Uint:    reflect.TypeOf(uint(0)),
+		reflect.Uint8:   reflect.TypeOf(uint8(0)),
+		reflect.Uint16:  reflect.TypeOf(uint16(0)),
+		reflect.Uint32:  reflect.TypeOf(uint32(0)),
+		reflect.Uint64:  reflect.TypeOf(uint64(0)),
+		reflect.Float32: reflect.TypeOf(float32(0)),
+		reflect.Float64: reflect.TypeOf(float64(0)),
+	}
+
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+)
+
+// RegisterParser makes GetAs, the collection getters and Load parse values of type T with parse. It replaces the
+// parser of T, including a built-in one. Call it during initialization, before the first lookup of T.
+func RegisterParser[T any](parse func(string) (T, error)) {
+	parsersMu.Lock()
+	defer parsersMu.Unlock()
+	addParser(parsers, func(raw string, _ *options) (T, error) {
+		return parse(raw)
+	})
+}
+
+// addParser adds the parser of T to m.
+func addParser[T any](m map[reflect.Type]parseFunc, parse func(string, *options) (T, error)) {
+	m[reflect.TypeOf((*T)(nil)).Elem()] = func(raw string, o *options) (any, error) {
+		return parse(raw, o)
+	}
+}
+
+// builtinParsers returns the parsers of the types supported out of the box.
+func builtinParsers() map[reflect.Type]parseFunc {
+	m := make(map[reflect.Type]parseFunc)
+	addParser(m, func(raw string, _ *options) (string, error) {
+		return raw, nil
+	})
+	addParser(m, parseBool)
+	addParser(m, intParser[int](strconv.IntSize))
+	addParser(m, intParser[int8](8))
+	addParser(m, intParser[int16](16))
+	addParser(m, intParser[int32](32))
+	addParser(m, intParser[int64](64))
+	addParser(m, uintParser[uint](strconv.IntSize))
+	addParser(m, uintParser[uint8](8))
+	addParser(m, uintParser[uint16](16))
+	addParser(m, uintParser[uint32](32))
+	addParser(m, uintParser[uint64](64))
+	addParser(m, func(raw string, _ *options) (float32, error) {
+		f, err := strconv.ParseFloat(raw, 32)
+		return float32(f), err
+	})
+	addParser(m, func(raw string, _ *options) (float64, error) {
+		return strconv.ParseFloat(raw, 64)
+	})
+	addParser(m, func(raw string, o *options) (time.Duration, error) {
+		return parseDuration(raw, o.unit)
+	})
+	addParser(m, func(raw string, _ *options) (time.Time, error) {
+		return parseTime(raw)
+	})
+	addParser(m, func(raw string, _ *options) (url.URL, error) {
+		u, err := url.Parse(raw)
+		if err != nil {
+			return url.URL{}, err
+		}
+		return *u, nil
+	})
+	addParser(m, func(raw string, _ *options) (net.IP, error) {
+		ip := net.ParseIP(strings.TrimSpace(raw))
+		if ip == nil {
+			return nil, errors.New("expected an IPv4 or IPv6 address")
+		}
+		return ip, nil
+	})
+	addParser(m, func(raw string, _ *options) (net.IPNet, error) {
+		_, ipNet, err := net.ParseCIDR(strings.TrimSpace(raw))
+		if err != nil {
+			return net.IPNet{}, errors.New("expected a network in CIDR notation, such as 192.168.0.0/16")
+		}
+		return *ipNet, nil
+	})
+	addParser(m, func(raw string, _ *options) (netip.AddrPort, error) {
+		return netip.ParseAddrPort(strings.TrimSpace(raw))
+	})
+	addParser(m, func(raw string, _ *options) (regexp.Regexp, error) {
+		re, err := regexp.Compile(raw)
+		if err != nil {
+			return regexp.Regexp{}, err
+		}
+		return *re, nil
+	})
+	return m
+}
+
+// intParser returns a parser for a signed integer type of the given size.
+func intParser[T int | int8 | int16 | int32 | int64](bitSize int) func(string, *options) (T, error) {
+	return func(raw string, _ *options) (T, error) {
+		i, err := parseInt(raw, bitSize)
+		return T(i), err
+	}
+}
+
+// uintParser returns a parser for an unsigned integer type of the given size.
+func uintParser[T uint | uint8 | uint16 | uint32 | uint64](bitSize int) func(string, *options) (T, error) {
+	return func(raw string, _ *options) (T, error) {
+		u, err := parseUint(raw, bitSize)
+		return T(u), err
+	}
+}
+
+// parseInt parses a signed integer of the given size. Like Go literals, it accepts the prefixes 0x, 0o and 0b and
+// underscores between digits. Unlike Go literals, a leading zero does not make a number octal. A *RangeError is
+// returned if the number does not fit.
+func parseInt(raw string, bitSize int) (int64, error) {
+	i, err := strconv.ParseInt(intLiteral(raw), 0, bitSize)
+	if errors.Is(err, strconv.ErrRange) {
+		return 0, &RangeError{Min: -1 << (bitSize - 1), Max: 1<<(bitSize-1) - 1}
+	}
+	return i, err
+}
+
+// parseUint parses an unsigned integer of the given size, see parseInt. Negative numbers are out of range.
+func parseUint(raw string, bitSize int) (uint64, error) {
+	u, err := strconv.ParseUint(intLiteral(raw), 0, bitSize)
+	if err != nil && strings.HasPrefix(raw, "-") {
+		// "-0" is zero; only values that are really below it are out of range
+		signed, signedErr := strconv.ParseInt(intLiteral(raw), 0, 64)
+		switch {
+		case signedErr == nil && signed == 0:
+			return 0, nil
+		case signedErr == nil && signed < 0, errors.Is(signedErr, strconv.ErrRange):
+			err = strconv.ErrRange
+		}
+	}
+	if errors.Is(err, strconv.ErrRange) {
+		return 0, &RangeError{Min: 0, Max: math.MaxUint64 >> (64 - bitSize)}
+	}
+	return u, err
+}
+
+// intLiteral removes the leading zeros of a decimal number, so that strconv does not read it as octal.
+func intLiteral(s string) string {
+	sign := ""
+	if s != "" && (s[0] == '+' || s[0] == '-') {
+		sign, s = s[:1], s[1:]
+	}
+	if len(s) < 2 || s[0] != '0' {
+		return sign + s
+	}
+	switch s[1] {
+	case 'x', 'X', 'o', 'O', 'b', 'B':
+		return sign + s
+	}
+	if s = strings.TrimLeft(s, "0"); s == "" {
+		s = "0"
+	}
+	return sign + s
+}
+
+// parseAs parses raw into a T using the same rules as parseValue.
+func parseAs[T any](raw string, o *options) (T, error) {
+	var v T
+	err := parseValue(reflect.ValueOf(&v).Elem(), raw, o)
+	return v, err
+}
+
+// parseValue parses raw into dst. Types with a registered parser use it; other types implementing
+// encoding.TextUnmarshaler use UnmarshalText; named types of a basic kind, such as `type Level string`, use the
+// parser of their kind; pointers use the parser of the type they point to. Everything else is unmarshaled from JSON.
+func parseValue(dst reflect.Value, raw string, o *options) error {
+	if parse := textParser(dst.Type()); parse != nil {
+		v, err := parse(raw, o)
+		if err != nil {
+			return err
+		}
+		dst.Set(v)
+		return nil
+	}
+
+	target := reflect.New(dst.Type())
+	if err := decodeValue(raw, target.Interface(), o); err != nil {
+		return err
+	}
+	dst.Set(target.Elem())
+	return nil
+}
+
+// textParser returns a function parsing raw text into a value of type t, or nil if t is unmarshaled from JSON.
+func textParser(t reflect.Type) func(string, *options) (reflect.Value, error) {
+	parsersMu.RLock()
+	parse, ok := parsers[t]
+	if !ok {
+		if basic, isBasic := kindTypes[t.Kind()]; isBasic {
+			parse = parsers[basic]
+		}
+	}
+	parsersMu.RUnlock()
+
+	switch {
+	case ok:
+		return func(raw string, o *options) (reflect.Value, error) {
+			v, err := parse(raw, o)
+			if err != nil {
+				return reflect.Value{}, err
+			}
+			return reflect.ValueOf(v), nil
+		}
+	case reflect.PointerTo(t).Implements(textUnmarshalerType):
+		return func(raw string, _ *options) (reflect.Value, error) {
+			target := reflect.New(t)
+			if err := target.Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(raw)); err != nil {
+				return reflect.Value{}, err
+			}
+			return target.Elem(), nil
+		}
+	case t.Kind() == reflect.Pointer:
+		parseElem := textParser(t.Elem())
+		if parseElem == nil {
+			return nil
+		}
+		return func(raw string, o *options) (reflect.Value, error) {
+			elem, err := parseElem(raw, o)
+			if err != nil {
+				return reflect.Value{}, err
+			}
+			target := reflect.New(t.Elem())
+			target.Elem().Set(elem)
+			return target, nil
+		}
+	case parse != nil:
+		return func(raw string, o *options) (reflect.Value, error) {
+			v, err := parse(raw, o)
+			if err != nil {
+				return reflect.Value{}, err
+			}
+			return reflect.ValueOf(v).Convert(t), nil
+		}
+	default:
+		return nil
+	}
+}
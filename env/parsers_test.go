@@ -0,0 +1,421 @@
+package env
+
+/*
+Copyright 2023 UMH Systems GmbH
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"net"
+	"net/netip"
+	"net/url"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/united-manufacturing-hub/umh-utils/parse"
+)
+
+// TestGetAsDuration tests the GetAsDuration function
+func TestGetAsDuration(t *testing.T) {
+	t.Parallel()
+
+	r := NewReader(MapSource{
+		"GO_SYNTAX": "1m30s",
+		"BARE":      "30",
+		"FRACTION":  "1.5",
+		"WRONG":     "wrong",
+		"EMPTY":     "",
+		"MAX":       "9223372036.854775807",
+		"NEAR_MAX":  "9223372036",
+	})
+	fallback := 5 * time.Second
+
+	tests := []struct {
+		name     string
+		key      string
+		opts     []Option
+		want     time.Duration
+		required bool
+		wantErr  bool
+	}{
+		{name: "Case 1: Go duration syntax", key: "GO_SYNTAX", want: 90 * time.Second},
+		{name: "Case 2: Bare number defaults to seconds", key: "BARE", want: 30 * time.Second},
+		{name: "Case 3: Bare number with configured unit", key: "BARE", opts: []Option{Unit(time.Millisecond)}, want: 30 * time.Millisecond},
+		{name: "Case 4: Fractional bare number", key: "FRACTION", want: 1500 * time.Millisecond},
+		{name: "Case 5: Variable does not exist and is not required, return fallback value", key: "NONEXISTENT", want: fallback},
+		{name: "Case 6: Variable does not exist and is required, return error", key: "NONEXISTENT", required: true, want: 0, wantErr: true},
+		{name: "Case 7: Variable is not a duration, return fallback value and error", key: "WRONG", want: fallback, wantErr: true},
+		{name: "Case 8: Variable is empty, return fallback value and error", key: "EMPTY", want: fallback, wantErr: true},
+		{name: "Case 9: Bare number rounding to 2^63 nanoseconds is out of range", key: "MAX", want: fallback, wantErr: true},
+		{name: "Case 10: Bare number just below the limit", key: "NEAR_MAX", want: 9223372036 * time.Second},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := r.GetAsDuration(tt.key, tt.required, fallback, tt.opts...)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("GetAsDuration() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if got != tt.want {
+				t.Errorf("GetAsDuration() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestGetAsTime tests the GetAsTime function
+func TestGetAsTime(t *testing.T) {
+	t.Parallel()
+
+	r := NewReader(MapSource{
+		"RFC3339":      "2023-10-11T12:13:14Z",
+		"RFC3339_NANO": "2023-10-11T14:13:14.5+02:00",
+		"UNIX":         "1697026394",
+		"UNIX_MILLI":   "1697026394500",
+		"WRONG":        "yesterday",
+	})
+	fallback := time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)
+	want := time.Date(2023, 10, 11, 12, 13, 14, 0, time.UTC)
+
+	tests := []struct {
+		want     time.Time
+		name     string
+		key      string
+		required bool
+		wantErr  bool
+	}{
+		{name: "Case 1: RFC 3339", key: "RFC3339", want: want},
+		{name: "Case 2: RFC 3339 with fraction and offset", key: "RFC3339_NANO", want: want.Add(500 * time.Millisecond)},
+		{name: "Case 3: Unix seconds", key: "UNIX", want: want},
+		{name: "Case 4: Unix milliseconds", key: "UNIX_MILLI", want: want.Add(500 * time.Millisecond)},
+		{name: "Case 5: Variable does not exist and is not required, return fallback value", key: "NONEXISTENT", want: fallback},
+		{name: "Case 6: Variable does not exist and is required, return error", key: "NONEXISTENT", required: true, wantErr: true},
+		{name: "Case 7: Variable is not a time, return fallback value and error", key: "WRONG", want: fallback, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := r.GetAsTime(tt.key, tt.required, fallback)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("GetAsTime() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !got.Equal(tt.want) {
+				t.Errorf("GetAsTime() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestLoadDurationAndTime tests that Load handles time.Duration and time.Time fields
+func TestLoadDurationAndTime(t *testing.T) {
+	t.Parallel()
+
+	var cfg struct {
+		Timeout   time.Duration `env:"TIMEOUT" default:"10"`
+		KeepAlive time.Duration `env:"KEEPALIVE" default:"1m"`
+		Since     time.Time     `env:"SINCE"`
+	}
+	r := NewReader(MapSource{"KEEPALIVE": "2m", "SINCE": "1697026394"})
+	if err := r.Load(&cfg); err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if cfg.Timeout != 10*time.Second || cfg.KeepAlive != 2*time.Minute || cfg.Since.Unix() != 1697026394 {
+		t.Errorf("Load() = %+v", cfg)
+	}
+}
+
+// TestGetAsQuantity tests the GetAsQuantity function
+func TestGetAsQuantity(t *testing.T) {
+	t.Parallel()
+
+	r := NewReader(MapSource{
+		"BINARY":  "10Mi",
+		"DECIMAL": "1.5M",
+		"PLAIN":   "1024",
+		"SUFFIX":  "10Ti",
+	})
+
+	tests := []struct {
+		wantErrIs error
+		name      string
+		key       string
+		want      int
+		required  bool
+		wantErr   bool
+	}{
+		{name: "Case 1: Binary suffix", key: "BINARY", want: 10 * 1024 * 1024},
+		{name: "Case 2: Decimal suffix", key: "DECIMAL", want: 1500000},
+		{name: "Case 3: No suffix", key: "PLAIN", want: 1024},
+		{name: "Case 4: Variable does not exist and is not required, return fallback value", key: "NONEXISTENT", want: 42},
+		{name: "Case 5: Variable does not exist and is required, return error", key: "NONEXISTENT", required: true, wantErr: true, wantErrIs: ErrNotSet},
+		{name: "Case 6: Unknown suffix, return fallback value and error", key: "SUFFIX", want: 42, wantErr: true, wantErrIs: parse.ErrSuffix},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := r.GetAsQuantity(tt.key, tt.required, 42)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("GetAsQuantity() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if tt.wantErrIs != nil && !errors.Is(err, tt.wantErrIs) {
+				t.Errorf("GetAsQuantity() error = %v, want %v", err, tt.wantErrIs)
+			}
+			if got != tt.want {
+				t.Errorf("GetAsQuantity() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestLoadQuantity tests the quantity flag of the env tag
+func TestLoadQuantity(t *testing.T) {
+	t.Parallel()
+
+	var cfg struct {
+		MaxMessageBytes int `env:"MAX_MESSAGE_BYTES,quantity"`
+		BufferBytes     int `env:"BUFFER_BYTES,quantity" default:"1Ki"`
+	}
+	r := NewReader(MapSource{"MAX_MESSAGE_BYTES": "10Mi"})
+	if err := r.Load(&cfg); err != nil || cfg.MaxMessageBytes != 10*1024*1024 || cfg.BufferBytes != 1024 {
+		t.Errorf("Load() = %+v, %v", cfg, err)
+	}
+
+	var invalid struct {
+		Ratio float64 `env:"RATIO,quantity"`
+	}
+	if err := r.Load(&invalid); err == nil {
+		t.Error("Load() expected error for a quantity on a float64 field")
+	}
+}
+
+// parserTestLevel is a named string type, parsed by the parser of its kind.
+type parserTestLevel string
+
+// parserTestColor implements encoding.TextUnmarshaler.
+type parserTestColor struct {
+	R, G, B uint8
+}
+
+func (c *parserTestColor) UnmarshalText(text []byte) error {
+	_, err := fmt.Sscanf(string(text), "#%02x%02x%02x", &c.R, &c.G, &c.B)
+	return err
+}
+
+// parserTestVersion has a parser registered by TestRegisterParser.
+type parserTestVersion struct {
+	Major, Minor int
+}
+
+// TestGetAs tests the built-in parsers of GetAs
+func TestGetAs(t *testing.T) {
+	t.Parallel()
+
+	r := NewReader(MapSource{
+		"INT8":      "-128",
+		"INT8_BIG":  "128",
+		"UINT16":    "65535",
+		"UINT":      "42",
+		"FLOAT32":   "0.5",
+		"URL":       "mqtt://broker:1883/umh",
+		"IP":        "10.0.0.1",
+		"IP_WRONG":  "10.0.0",
+		"CIDR":      "192.168.1.7/16",
+		"ADDR_PORT": "[::1]:8080",
+		"REGEX":     "^umh/v1/.+$",
+		"REGEX_BAD": "(",
+		"LEVEL":     "debug",
+		"COLOR":     "#ff8000",
+		"PREFIX":    "10.0.0.0/8",
+		"TOPICS":    `["a","b"]`,
+	})
+
+	check := func(name string, got any, err error, want any) {
+		t.Helper()
+		if err != nil {
+			t.Errorf("%s: GetAs() error = %v", name, err)
+			return
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("%s: GetAs() = %#v, want %#v", name, got, want)
+		}
+	}
+
+	i8, err := GetAsFrom[int8](r, "INT8", true, 0)
+	check("int8", i8, err, int8(-128))
+	u16, err := GetAsFrom[uint16](r, "UINT16", true, 0)
+	check("uint16", u16, err, uint16(65535))
+	u, err := GetAsFrom[uint](r, "UINT", true, 0)
+	check("uint", u, err, uint(42))
+	f32, err := GetAsFrom[float32](r, "FLOAT32", true, 0)
+	check("float32", f32, err, float32(0.5))
+	brokerURL, err := GetAsFrom(r, "URL", true, url.URL{})
+	check("url.URL", brokerURL, err, url.URL{Scheme: "mqtt", Host: "broker:1883", Path: "/umh"})
+	brokerURLPtr, err := GetAsFrom[*url.URL](r, "URL", true, nil)
+	check("*url.URL", brokerURLPtr, err, &url.URL{Scheme: "mqtt", Host: "broker:1883", Path: "/umh"})
+	ip, err := GetAsFrom[net.IP](r, "IP", true, nil)
+	check("net.IP", ip, err, net.ParseIP("10.0.0.1"))
+	ipNet, err := GetAsFrom(r, "CIDR", true, net.IPNet{})
+	check("net.IPNet", ipNet.String(), err, "192.168.0.0/16")
+	addrPort, err := GetAsFrom(r, "ADDR_PORT", true, netip.AddrPort{})
+	check("netip.AddrPort", addrPort, err, netip.MustParseAddrPort("[::1]:8080"))
+	re, err := GetAsFrom(r, "REGEX", true, regexp.Regexp{})
+	check("regexp.Regexp", re.MatchString("umh/v1/topic"), err, true)
+	level, err := GetAsFrom[parserTestLevel](r, "LEVEL", true, "")
+	check("named string", level, err, parserTestLevel("debug"))
+	color, err := GetAsFrom(r, "COLOR", true, parserTestColor{})
+	check("TextUnmarshaler", color, err, parserTestColor{R: 0xff, G: 0x80})
+	prefix, err := GetAsFrom(r, "PREFIX", true, netip.Prefix{})
+	check("netip.Prefix", prefix, err, netip.MustParsePrefix("10.0.0.0/8"))
+	topics, err := GetAsFrom[[]string](r, "TOPICS", true, nil)
+	check("JSON", topics, err, []string{"a", "b"})
+	fallback, err := GetAsFrom(r, "NONEXISTENT", false, 7*time.Second)
+	check("fallback", fallback, err, 7*time.Second)
+
+	var parseErr *ParseError
+	if got, err := GetAsFrom[int8](r, "INT8_BIG", false, 1); !errors.As(err, &parseErr) || got != 1 {
+		t.Errorf("GetAs[int8]() = %d, %v, want fallback and *ParseError", got, err)
+	}
+	if _, err := GetAsFrom[net.IP](r, "IP_WRONG", false, nil); !errors.As(err, &parseErr) || parseErr.Type != "net.IP" {
+		t.Errorf("GetAs[net.IP]() error = %v, want *ParseError", err)
+	}
+	if _, err := GetAsFrom(r, "REGEX_BAD", false, regexp.Regexp{}); !errors.As(err, &parseErr) {
+		t.Errorf("GetAs[regexp.Regexp]() error = %v, want *ParseError", err)
+	}
+}
+
+// TestRegisterParser tests that registered parsers are used by GetAs, the collection getters and Load
+func TestRegisterParser(t *testing.T) {
+	t.Parallel()
+
+	RegisterParser(func(raw string) (parserTestVersion, error) {
+		var v parserTestVersion
+		if _, err := fmt.Sscanf(strings.TrimPrefix(raw, "v"), "%d.%d", &v.Major, &v.Minor); err != nil {
+			return parserTestVersion{}, errors.New("expected a version such as v1.2")
+		}
+		return v, nil
+	})
+
+	r := NewReader(MapSource{"VERSION": "v1.2", "VERSIONS": "v1.0,v2.1", "ADDRESSES": `["10.0.0.1","::1"]`, "WRONG": "latest"})
+	if got, err := GetAsFrom(r, "VERSION", true, parserTestVersion{}); err != nil || got != (parserTestVersion{1, 2}) {
+		t.Errorf("GetAs() = %v, %v", got, err)
+	}
+	if got, err := GetAsSliceFrom[parserTestVersion](r, "VERSIONS", true, nil); err != nil || !reflect.DeepEqual(got, []parserTestVersion{{1, 0}, {2, 1}}) {
+		t.Errorf("GetAsSlice() = %v, %v", got, err)
+	}
+	if _, err := GetAsFrom(r, "WRONG", true, parserTestVersion{}); err == nil || !strings.Contains(err.Error(), "expected a version") {
+		t.Errorf("GetAs() error = %v, want the parser error", err)
+	}
+
+	var cfg struct {
+		Version   parserTestVersion `env:"VERSION"`
+		Addresses []net.IP          `env:"ADDRESSES"`
+		Network   *net.IPNet        `env:"NETWORK" default:"10.0.0.0/8"`
+	}
+	if err := r.Load(&cfg); err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if cfg.Version != (parserTestVersion{1, 2}) || len(cfg.Addresses) != 2 || cfg.Network.String() != "10.0.0.0/8" {
+		t.Errorf("Load() = %+v", cfg)
+	}
+}
+
+// TestIntegerWidths tests the integer getters, their bases and their bounds
+func TestIntegerWidths(t *testing.T) {
+	t.Parallel()
+
+	r := NewReader(MapSource{
+		"HEX":        "0x9C41",
+		"OCTAL":      "0o17",
+		"BINARY":     "-0b1010",
+		"UNDERSCORE": "1_000_000",
+		"LEADING":    "010",
+		"MAX_INT8":   "127",
+		"BIG_INT8":   "128",
+		"SMALL_INT8": "-129",
+		"BIG_UINT16": "0x10000",
+		"NEGATIVE":   "-1",
+		"MINUS_ZERO": "-0",
+		"HUGE":       "99999999999999999999",
+		"WRONG":      "0x",
+	})
+
+	tests := []struct {
+		get     func(key string) (any, error)
+		want    any
+		name    string
+		key     string
+		wantMin int64
+		wantMax uint64
+		wantErr bool
+	}{
+		{name: "Case 1: Hexadecimal", key: "HEX", get: func(k string) (any, error) { return r.GetAsUint16(k, true, 0) }, want: uint16(0x9C41)},
+		{name: "Case 2: Octal", key: "OCTAL", get: func(k string) (any, error) { return r.GetAsInt(k, true, 0) }, want: 15},
+		{name: "Case 3: Negative binary", key: "BINARY", get: func(k string) (any, error) { return r.GetAsInt8(k, true, 0) }, want: int8(-10)},
+		{name: "Case 4: Underscores", key: "UNDERSCORE", get: func(k string) (any, error) { return r.GetAsInt32(k, true, 0) }, want: int32(1_000_000)},
+		{name: "Case 5: Leading zeros are decimal", key: "LEADING", get: func(k string) (any, error) { return r.GetAsUint(k, true, 0) }, want: uint(10)},
+		{name: "Case 6: Largest int8", key: "MAX_INT8", get: func(k string) (any, error) { return r.GetAsInt8(k, true, 0) }, want: int8(127)},
+		{name: "Case 7: int8 overflow", key: "BIG_INT8", get: func(k string) (any, error) { return r.GetAsInt8(k, true, 0) }, wantErr: true, wantMin: -128, wantMax: 127},
+		{name: "Case 8: int8 underflow", key: "SMALL_INT8", get: func(k string) (any, error) { return r.GetAsInt8(k, true, 0) }, wantErr: true, wantMin: -128, wantMax: 127},
+		{name: "Case 9: uint16 overflow", key: "BIG_UINT16", get: func(k string) (any, error) { return r.GetAsUint16(k, true, 0) }, wantErr: true, wantMax: 65535},
+		{name: "Case 10: Negative uint8", key: "NEGATIVE", get: func(k string) (any, error) { return r.GetAsUint8(k, true, 0) }, wantErr: true, wantMax: 255},
+		{name: "Case 11: int64 overflow", key: "HUGE", get: func(k string) (any, error) { return r.GetAsInt64(k, true, 0) }, wantErr: true, wantMin: math.MinInt64, wantMax: math.MaxInt64},
+		{name: "Case 12: uint32 overflow", key: "HUGE", get: func(k string) (any, error) { return r.GetAsUint32(k, true, 0) }, wantErr: true, wantMax: math.MaxUint32},
+		{name: "Case 13: Hexadecimal uint64", key: "HEX", get: func(k string) (any, error) { return r.GetAsUint64(k, true, 0) }, want: uint64(0x9C41)},
+		{name: "Case 14: Int16", key: "OCTAL", get: func(k string) (any, error) { return r.GetAsInt16(k, true, 0) }, want: int16(15)},
+		{name: "Case 15: Prefix without digits", key: "WRONG", get: func(k string) (any, error) { return r.GetAsInt(k, true, 0) }, wantErr: true},
+		{name: "Case 16: Negative zero uint8", key: "MINUS_ZERO", get: func(k string) (any, error) { return r.GetAsUint8(k, true, 0) }, want: uint8(0)},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			got, err := tt.get(tt.key)
+			if !tt.wantErr {
+				if err != nil || got != tt.want {
+					t.Errorf("got %v (%T), %v, want %v (%T)", got, got, err, tt.want, tt.want)
+				}
+				return
+			}
+
+			var parseErr *ParseError
+			if !errors.As(err, &parseErr) || parseErr.Key != tt.key {
+				t.Fatalf("error = %v, want *ParseError for %s", err, tt.key)
+			}
+			if tt.wantMin == 0 && tt.wantMax == 0 {
+				return
+			}
+			var rangeErr *RangeError
+			if !errors.As(err, &rangeErr) || !errors.Is(err, strconv.ErrRange) {
+				t.Fatalf("error = %v, want *RangeError", err)
+			}
+			if rangeErr.Min != tt.wantMin || rangeErr.Max != tt.wantMax {
+				t.Errorf("bounds = [%d, %d], want [%d, %d]", rangeErr.Min, rangeErr.Max, tt.wantMin, tt.wantMax)
+			}
+			if want := fmt.Sprintf("between %d and %d", tt.wantMin, tt.wantMax); !strings.Contains(err.Error(), want) {
+				t.Errorf("error = %q, want it to contain %q", err, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,123 @@
+package env
+
+/*
+Copyright 2023 UMH Systems GmbH
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+import (
+	"errors"
+	"strings"
+	"sync/atomic"
+
+	"go.uber.org/zap"
+)
+
+// Policy decides what a lookup returns if the value of a variable is malformed, i.e. cannot be parsed or violates
+// a validation rule.
+type Policy int32
+
+const (
+	// PolicyFallback returns the fallback value together with the error. This is the default.
+	PolicyFallback Policy = iota
+	// PolicyStrict returns the zero value together with the error, so the fallback cannot be used by accident.
+	PolicyStrict
+	// PolicyLenient returns the fallback value without an error and logs a warning instead.
+	PolicyLenient
+	// PolicyRequiredStrict is PolicyStrict, and additionally rejects required variables that are set to an empty
+	// or whitespace-only string with a *NotSetError.
+	PolicyRequiredStrict
+)
+
+// defaultPolicy is the policy of lookups without a WithPolicy option.
+var defaultPolicy atomic.Int32
+
+// SetDefaultPolicy sets the policy of all lookups without a WithPolicy option.
+func SetDefaultPolicy(p Policy) {
+	defaultPolicy.Store(int32(p))
+}
+
+// DefaultPolicy returns the policy set by SetDefaultPolicy.
+func DefaultPolicy() Policy {
+	return Policy(defaultPolicy.Load())
+}
+
+// String returns the name of the policy.
+func (p Policy) String() string {
+	switch p {
+	case PolicyFallback:
+		return "fallback"
+	case PolicyStrict:
+		return "strict"
+	case PolicyLenient:
+		return "lenient"
+	case PolicyRequiredStrict:
+		return "required-strict"
+	default:
+		return "unknown"
+	}
+}
+
+// WithPolicy sets the policy of the lookup, overriding the default policy.
+func WithPolicy(p Policy) Option {
+	return func(o *options) {
+		o.policy = p
+	}
+}
+
+// WithLogger sets the logger used for warnings, such as those of PolicyLenient. The default is the global logger of
+// zap, which the logger package sets up.
+func WithLogger(logger *zap.SugaredLogger) Option {
+	return func(o *options) {
+		o.logger = logger
+	}
+}
+
+// rejectsEmpty reports whether the value of a required variable must be treated as not set.
+func (o *options) rejectsEmpty(required bool, value string) bool {
+	return required && o.policy == PolicyRequiredStrict && strings.TrimSpace(value) == ""
+}
+
+// applyPolicy returns the error of the lookup of key after applying the policy, and whether the zero value must be
+// returned instead of the fallback.
+func (o *options) applyPolicy(key string, err error) (zero bool, _ error) {
+	if err == nil {
+		return false, nil
+	}
+	switch o.policy {
+	case PolicyStrict, PolicyRequiredStrict:
+		return true, err
+	case PolicyLenient:
+		var parseErr *ParseError
+		var validationErr *ValidationError
+		if errors.As(err, &parseErr) || errors.As(err, &validationErr) {
+			o.warn("Invalid environment variable, using the fallback value", "key", key, "error", err)
+			return false, nil
+		}
+	}
+	return false, err
+}
+
+// warn logs a warning through the logger of o.
+func (o *options) warn(msg string, keysAndValues ...any) {
+	o.log().Warnw(msg, keysAndValues...)
+}
+
+// log returns the logger of o, or the global logger of zap if none is set.
+func (o *options) log() *zap.SugaredLogger {
+	if o.logger == nil {
+		return zap.S()
+	}
+	return o.logger
+}
@@ -0,0 +1,134 @@
+package env
+
+/*
+Copyright 2023 UMH Systems GmbH
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+import (
+	"errors"
+	"testing"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+// TestPolicy tests how each policy handles malformed, empty and missing values
+func TestPolicy(t *testing.T) {
+	t.Parallel()
+
+	r := NewReader(MapSource{"POLICY_WRONG": "wrong", "POLICY_EMPTY": " ", "POLICY_BIG": "99999"})
+	tests := []struct {
+		wantErr  error
+		name     string
+		key      string
+		policy   Policy
+		required bool
+		want     int
+		wantWarn bool
+	}{
+		{name: "Case 1: Fallback returns fallback and error", key: "POLICY_WRONG", policy: PolicyFallback, want: 42, wantErr: &ParseError{}},
+		{name: "Case 2: Strict returns zero and error", key: "POLICY_WRONG", policy: PolicyStrict, want: 0, wantErr: &ParseError{}},
+		{name: "Case 3: Lenient returns fallback and warns", key: "POLICY_WRONG", policy: PolicyLenient, want: 42, wantWarn: true},
+		{name: "Case 4: Lenient ignores validation errors", key: "POLICY_BIG", policy: PolicyLenient, want: 42, wantWarn: true},
+		{name: "Case 5: Strict returns zero on validation errors", key: "POLICY_BIG", policy: PolicyStrict, want: 0, wantErr: &ValidationError{}},
+		{name: "Case 6: Lenient still reports missing required variables", key: "POLICY_NONEXISTENT", policy: PolicyLenient, required: true, want: 0, wantErr: &NotSetError{}},
+		{name: "Case 7: Strict falls back for optional unset variables", key: "POLICY_NONEXISTENT", policy: PolicyStrict, want: 42},
+		{name: "Case 8: Strict treats empty values as malformed", key: "POLICY_EMPTY", policy: PolicyStrict, required: true, want: 0, wantErr: &ParseError{}},
+		{name: "Case 9: Required-strict rejects empty required values", key: "POLICY_EMPTY", policy: PolicyRequiredStrict, required: true, want: 0, wantErr: &NotSetError{}},
+		{name: "Case 10: Required-strict returns zero on parse errors", key: "POLICY_EMPTY", policy: PolicyRequiredStrict, want: 0, wantErr: &ParseError{}},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			core, logs := observer.New(zapcore.WarnLevel)
+			got, err := r.GetAsInt(tt.key, tt.required, 42, Max(1000), WithPolicy(tt.policy), WithLogger(zap.New(core).Sugar()))
+			if got != tt.want {
+				t.Errorf("GetAsInt() = %d, want %d", got, tt.want)
+			}
+			switch target := tt.wantErr.(type) {
+			case nil:
+				if err != nil {
+					t.Errorf("GetAsInt() error = %v, want nil", err)
+				}
+			case *ParseError:
+				if !errors.As(err, &target) {
+					t.Errorf("GetAsInt() error = %v, want *ParseError", err)
+				}
+			case *ValidationError:
+				if !errors.As(err, &target) {
+					t.Errorf("GetAsInt() error = %v, want *ValidationError", err)
+				}
+			case *NotSetError:
+				if !errors.Is(err, ErrNotSet) {
+					t.Errorf("GetAsInt() error = %v, want ErrNotSet", err)
+				}
+			}
+			if warned := logs.Len() > 0; warned != tt.wantWarn {
+				t.Errorf("warned = %v, want %v", warned, tt.wantWarn)
+			}
+		})
+	}
+}
+
+// TestPolicyLoad tests that policies apply to struct fields and reader options
+func TestPolicyLoad(t *testing.T) {
+	t.Parallel()
+
+	source := MapSource{"POLICY_PORT": "http", "POLICY_TOPICS": "[", "POLICY_NAME": ""}
+	type config struct {
+		Port   int      `env:"POLICY_PORT" default:"8080"`
+		Topics []string `env:"POLICY_TOPICS" default:"[\"a\"]"`
+		Name   string   `env:"POLICY_NAME,required"`
+	}
+
+	var cfg config
+	err := NewReader(source, WithPolicy(PolicyRequiredStrict)).Load(&cfg)
+	var errs Errors
+	if !errors.As(err, &errs) || len(errs) != 3 {
+		t.Fatalf("Load() error = %v, want 3 errors", err)
+	}
+	if cfg.Port != 0 || cfg.Topics != nil {
+		t.Errorf("Load() = %+v, want zero values", cfg)
+	}
+
+	cfg = config{}
+	err = NewReader(source, WithLogger(zap.NewNop().Sugar())).Load(&cfg, WithPolicy(PolicyLenient))
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if cfg.Port != 8080 || len(cfg.Topics) != 1 {
+		t.Errorf("Load() = %+v, want fallback values", cfg)
+	}
+}
+
+// TestSetDefaultPolicy tests the global policy
+func TestSetDefaultPolicy(t *testing.T) {
+	t.Setenv("POLICY_DEFAULT_WRONG", "wrong")
+	SetDefaultPolicy(PolicyStrict)
+	defer SetDefaultPolicy(PolicyFallback)
+
+	if DefaultPolicy() != PolicyStrict || DefaultPolicy().String() != "strict" {
+		t.Errorf("DefaultPolicy() = %v", DefaultPolicy())
+	}
+	if got, err := GetAsInt("POLICY_DEFAULT_WRONG", false, 42); got != 0 || err == nil {
+		t.Errorf("GetAsInt() = %d, %v, want zero value and error", got, err)
+	}
+	if got, err := GetAsInt("POLICY_DEFAULT_WRONG", false, 42, WithPolicy(PolicyFallback)); got != 42 || err == nil {
+		t.Errorf("GetAsInt() = %d, %v, want fallback and error", got, err)
+	}
+}
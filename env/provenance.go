@@ -0,0 +1,234 @@
+package env
+
+/*
+Copyright 2023 UMH Systems GmbH
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// Origin tells where the value of a variable came from.
+type Origin string
+
+const (
+	OriginEnv     = Origin("env")     // the environment of the process
+	OriginFile    = Origin("file")    // the file named by KEY_FILE
+	OriginDotenv  = Origin("dotenv")  // a .env file loaded with LoadDotenv or OverloadDotenv
+	OriginMap     = Origin("map")     // a MapSource
+	OriginDir     = Origin("dir")     // a DirSource
+	OriginConfig  = Origin("config")  // a configuration file, see LoadLayered
+	OriginFlag    = Origin("flag")    // a command-line flag, see LoadLayered
+	OriginSource  = Origin("source")  // a Source that does not implement OriginReporter
+	OriginDefault = Origin("default") // the fallback value
+	OriginUnset   = Origin("unset")   // a required variable that is not set
+)
+
+// OriginReporter is implemented by sources that can tell where a value came from. Origin is only called for keys
+// the source has set. path names the file the value was read from, if any.
+type OriginReporter interface {
+	Origin(key string) (origin Origin, path string)
+}
+
+// Resolved describes the value a lookup resolved to.
+type Resolved struct {
+	// Key is the name of the variable.
+	Key string
+	// Value is the effective value, formatted as it would be written in the environment. It is "[REDACTED]" for
+	// sensitive variables and for values read from a KEY_FILE.
+	Value string
+	// Origin tells where Value came from.
+	Origin Origin
+	// Path is the file Value was read from, if any. For OriginFlag, it is the flag instead, e.g. "-kafka-brokers".
+	Path string
+	// Alias is the name the value was read from, if it is not Key.
+	Alias string
+	// Error is the message of the lookup error. Value is the fallback in this case.
+	Error string
+	// Sensitive is set for secrets, which include the values read from a KEY_FILE.
+	Sensitive bool
+}
+
+// Snapshot is the effective configuration: the last resolved value of every variable that has been looked up,
+// sorted by key.
+type Snapshot []Resolved
+
+// location is the origin of a raw value.
+type location struct {
+	origin Origin
+	path   string
+	alias  string
+}
+
+// dotenvOrigins maps the variables set by LoadDotenv and OverloadDotenv to the file and value they were set from.
+var dotenvOrigins sync.Map
+
+// dotenvOrigin is a value of dotenvOrigins.
+type dotenvOrigin struct {
+	path  string
+	value string
+}
+
+// CurrentSnapshot returns the effective configuration of the package-level functions, e.g. to log it at startup:
+//
+//	env.CurrentSnapshot().Log(logger.New("PRODUCTION"))
+func CurrentSnapshot() Snapshot {
+	return defaultReader.Snapshot()
+}
+
+// Snapshot returns the effective configuration of the lookups performed by r.
+func (r *Reader) Snapshot() Snapshot {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	s := make(Snapshot, 0, len(r.resolved))
+	for _, res := range r.resolved {
+		s = append(s, res)
+	}
+	sort.Slice(s, func(i, j int) bool {
+		return s[i].Key < s[j].Key
+	})
+	return s
+}
+
+// record stores the outcome of a lookup of key, masking the value if it is sensitive. Values read from a KEY_FILE
+// count as sensitive, as that is how Docker and Kubernetes pass secrets.
+func (r *Reader) record(key string, loc location, value any, err error, o *options) {
+	res := Resolved{Key: key, Origin: loc.origin, Path: loc.path, Alias: loc.alias, Sensitive: o.sensitive || loc.origin == OriginFile}
+	if loc.origin != OriginUnset {
+		res.Value = formatValue(value, o)
+	}
+	if res.Sensitive && res.Value != "" {
+		res.Value = redacted
+	}
+	if err != nil {
+		res.Error = err.Error()
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.resolved[key] = res
+}
+
+// originOf returns the origin of key, which source has set.
+func originOf(source Source, key string) location {
+	reporter, ok := source.(OriginReporter)
+	if !ok {
+		return location{origin: OriginSource}
+	}
+	origin, path := reporter.Origin(key)
+	return location{origin: origin, path: path}
+}
+
+// Log writes the snapshot to logger at info level. A nil logger means the global logger of zap, which the logger
+// package sets up.
+func (s Snapshot) Log(logger *zap.SugaredLogger) {
+	if logger == nil {
+		logger = zap.S()
+	}
+	logger.Infow("Effective configuration", "variables", s)
+}
+
+// MarshalLogArray implements zapcore.ArrayMarshaler.
+func (s Snapshot) MarshalLogArray(enc zapcore.ArrayEncoder) error {
+	for _, res := range s {
+		if err := enc.AppendObject(res); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// String returns one line per variable, e.g. "PORT=8080 (env)".
+func (s Snapshot) String() string {
+	var b strings.Builder
+	for _, res := range s {
+		fmt.Fprintf(&b, "%s\n", res)
+	}
+	return b.String()
+}
+
+// MarshalLogObject implements zapcore.ObjectMarshaler.
+func (r Resolved) MarshalLogObject(enc zapcore.ObjectEncoder) error {
+	enc.AddString("key", r.Key)
+	enc.AddString("value", r.Value)
+	enc.AddString("origin", string(r.Origin))
+	if r.Path != "" {
+		enc.AddString("path", r.Path)
+	}
+	if r.Alias != "" {
+		enc.AddString("alias", r.Alias)
+	}
+	if r.Error != "" {
+		enc.AddString("error", r.Error)
+	}
+	return nil
+}
+
+// String returns the variable as KEY=value, followed by its origin and error.
+func (r Resolved) String() string {
+	s := fmt.Sprintf("%s=%s (%s", r.Key, r.Value, r.Origin)
+	if r.Path != "" {
+		s += " " + r.Path
+	}
+	if r.Alias != "" {
+		s += " as " + r.Alias
+	}
+	s += ")"
+	if r.Error != "" {
+		s += ": " + r.Error
+	}
+	return s
+}
+
+// Origin reports variables set by LoadDotenv or OverloadDotenv as OriginDotenv, unless they have been changed
+// since, and all others as OriginEnv.
+func (OSSource) Origin(key string) (Origin, string) {
+	if loaded, ok := dotenvOrigins.Load(key); ok {
+		origin, ok := loaded.(dotenvOrigin)
+		if value, set := os.LookupEnv(key); ok && set && value == origin.value {
+			return OriginDotenv, origin.path
+		}
+	}
+	return OriginEnv, ""
+}
+
+// Origin reports every variable as OriginMap.
+func (MapSource) Origin(string) (Origin, string) {
+	return OriginMap, ""
+}
+
+// Origin reports every variable as OriginDir, together with the path of its file.
+func (d DirSource) Origin(key string) (Origin, string) {
+	return OriginDir, filepath.Join(d.Path, key)
+}
+
+// Origin reports the origin of key in the first source that has it set.
+func (c ChainSource) Origin(key string) (Origin, string) {
+	for _, source := range c {
+		if _, ok, err := source.Lookup(key); err == nil && ok {
+			loc := originOf(source, key)
+			return loc.origin, loc.path
+		}
+	}
+	return OriginSource, ""
+}
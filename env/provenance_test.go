@@ -0,0 +1,153 @@
+package env
+
+/*
+Copyright 2023 UMH Systems GmbH
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+// TestSnapshot tests that a Reader records where each resolved value came from
+func TestSnapshot(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "PROV_DIR"), []byte("from-dir\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	secret := filepath.Join(dir, "secret")
+	if err := os.WriteFile(secret, []byte("s3cr3t\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	r := NewReader(ChainSource{
+		MapSource{"PROV_MAP": "8080", "PROV_WRONG": "wrong", "PROV_PASSWORD_FILE": secret, "PROV_TOKEN": "abc"},
+		DirSource{Path: dir},
+	})
+	_, _ = r.GetAsInt("PROV_MAP", false, 0)
+	_, _ = r.GetAsString("PROV_DIR", false, "")
+	// Values read from a KEY_FILE are redacted without Sensitive
+	_, _ = r.GetAsString("PROV_PASSWORD", false, "")
+	_, _ = r.GetAsInt("PROV_WRONG", false, 42)
+	_, _ = r.GetAsString("PROV_TOKEN", false, "", Sensitive())
+	_, _ = r.GetAsString("PROV_FALLBACK", false, "fallback")
+	_, _ = r.GetAsString("PROV_REQUIRED", true, "")
+	var topics []string
+	_ = GetAsTypeFrom(r, "PROV_TOPICS", &topics, false, []string{"a"})
+
+	want := Snapshot{
+		{Key: "PROV_DIR", Value: "from-dir", Origin: OriginDir, Path: filepath.Join(dir, "PROV_DIR")},
+		{Key: "PROV_FALLBACK", Value: "fallback", Origin: OriginDefault},
+		{Key: "PROV_MAP", Value: "8080", Origin: OriginMap},
+		{Key: "PROV_PASSWORD", Value: redacted, Origin: OriginFile, Path: secret, Sensitive: true},
+		{Key: "PROV_REQUIRED", Origin: OriginUnset, Error: "environment variable PROV_REQUIRED is required but not set (expected string)"},
+		{Key: "PROV_TOKEN", Value: redacted, Origin: OriginMap, Sensitive: true},
+		{Key: "PROV_TOPICS", Value: `["a"]`, Origin: OriginDefault},
+		{Key: "PROV_WRONG", Value: "42", Origin: OriginDefault, Error: `environment variable PROV_WRONG has value "wrong", which is not a valid int: invalid syntax`},
+	}
+	got := r.Snapshot()
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Snapshot() =\n%s\nwant\n%s", got, want)
+	}
+	if strings.Contains(got.String(), "s3cr3t") || strings.Contains(got.String(), "abc") {
+		t.Errorf("Snapshot() leaks a sensitive value:\n%s", got)
+	}
+}
+
+// TestSnapshotLoad tests that struct fields are recorded as well
+func TestSnapshotLoad(t *testing.T) {
+	t.Parallel()
+
+	r := NewReader(MapSource{"PROV_PORT": "8080", "PROV_BROKERS": `["a","b"]`})
+	var cfg struct {
+		Port    int      `env:"PROV_PORT"`
+		Brokers []string `env:"PROV_BROKERS"`
+		Level   string   `env:"PROV_LEVEL" default:"INFO"`
+	}
+	if err := r.Load(&cfg); err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+
+	want := Snapshot{
+		{Key: "PROV_BROKERS", Value: "a,b", Origin: OriginMap},
+		{Key: "PROV_LEVEL", Value: "INFO", Origin: OriginDefault},
+		{Key: "PROV_PORT", Value: "8080", Origin: OriginMap},
+	}
+	if got := r.Snapshot(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Snapshot() =\n%s\nwant\n%s", got, want)
+	}
+}
+
+// TestSnapshotDotenv tests that variables loaded from a .env file are reported as such
+func TestSnapshotDotenv(t *testing.T) {
+	t.Setenv("PROV_DOTENV", "")
+	t.Setenv("PROV_CHANGED", "")
+	t.Setenv("PROV_ENV", "from-env")
+
+	path := filepath.Join(t.TempDir(), ".env")
+	if err := os.WriteFile(path, []byte("PROV_DOTENV=from-file\nPROV_CHANGED=from-file\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := OverloadDotenv(path); err != nil {
+		t.Fatalf("OverloadDotenv() error = %v", err)
+	}
+	t.Setenv("PROV_CHANGED", "changed")
+
+	r := NewReader(OSSource{})
+	_, _ = r.GetAsString("PROV_DOTENV", false, "")
+	_, _ = r.GetAsString("PROV_CHANGED", false, "")
+	_, _ = r.GetAsString("PROV_ENV", false, "")
+
+	want := Snapshot{
+		{Key: "PROV_CHANGED", Value: "changed", Origin: OriginEnv},
+		{Key: "PROV_DOTENV", Value: "from-file", Origin: OriginDotenv, Path: path},
+		{Key: "PROV_ENV", Value: "from-env", Origin: OriginEnv},
+	}
+	if got := r.Snapshot(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Snapshot() =\n%s\nwant\n%s", got, want)
+	}
+}
+
+// TestSnapshotLog tests that a snapshot can be logged
+func TestSnapshotLog(t *testing.T) {
+	t.Parallel()
+
+	core, logs := observer.New(zap.InfoLevel)
+	s := Snapshot{
+		{Key: "PORT", Value: "8080", Origin: OriginEnv},
+		{Key: "PASSWORD", Value: redacted, Origin: OriginFile, Path: "/run/secrets/password", Sensitive: true},
+	}
+	s.Log(zap.New(core).Sugar())
+
+	entries := logs.All()
+	if len(entries) != 1 {
+		t.Fatalf("got %d log entries, want 1", len(entries))
+	}
+	want := []any{
+		map[string]any{"key": "PORT", "value": "8080", "origin": "env"},
+		map[string]any{"key": "PASSWORD", "value": redacted, "origin": "file", "path": "/run/secrets/password"},
+	}
+	if got := entries[0].ContextMap()["variables"]; !reflect.DeepEqual(got, want) {
+		t.Errorf("logged variables = %v, want %v", got, want)
+	}
+}
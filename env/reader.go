@@ -0,0 +1,295 @@
+package env
+
+/*
+Copyright 2023 UMH Systems GmbH
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/united-manufacturing-hub/umh-utils/parse"
+)
+
+// Reader performs lookups against a Source. The package-level GetAs* functions use a Reader bound to the
+// environment of the process. Every lookup also honors the KEY_FILE convention, see FileSuffix.
+type Reader struct {
+	source Source
+	opts   []Option
+	// resolved holds the outcome of the last lookup of every key, see Snapshot.
+	resolved map[string]Resolved
+	// warnedAliases holds the deprecated aliases that have been warned about, by aliasUse.
+	warnedAliases sync.Map
+	mu            sync.Mutex
+}
+
+// FileSuffix is appended to the name of a variable to read its value from a file instead, e.g. DB_PASSWORD_FILE.
+const FileSuffix = "_FILE"
+
+// defaultReader is used by the package-level functions.
+var defaultReader = NewReader(OSSource{})
+
+// NewReader returns a Reader for source. The options are applied to every lookup, before the options of the call.
+func NewReader(source Source, opts ...Option) *Reader {
+	return &Reader{source: source, opts: opts, resolved: make(map[string]Resolved)}
+}
+
+// GetAsString is GetAsString, reading from the source of r.
+func (r *Reader) GetAsString(key string, required bool, fallback string, opts ...Option) (string, error) {
+	return GetAsFrom(r, key, required, fallback, opts...)
+}
+
+// GetAsInt is GetAsInt, reading from the source of r.
+func (r *Reader) GetAsInt(key string, required bool, fallback int, opts ...Option) (int, error) {
+	return GetAsFrom(r, key, required, fallback, opts...)
+}
+
+// GetAsInt8 is GetAsInt8, reading from the source of r.
+func (r *Reader) GetAsInt8(key string, required bool, fallback int8, opts ...Option) (int8, error) {
+	return GetAsFrom(r, key, required, fallback, opts...)
+}
+
+// GetAsInt16 is GetAsInt16, reading from the source of r.
+func (r *Reader) GetAsInt16(key string, required bool, fallback int16, opts ...Option) (int16, error) {
+	return GetAsFrom(r, key, required, fallback, opts...)
+}
+
+// GetAsInt32 is GetAsInt32, reading from the source of r.
+func (r *Reader) GetAsInt32(key string, required bool, fallback int32, opts ...Option) (int32, error) {
+	return GetAsFrom(r, key, required, fallback, opts...)
+}
+
+// GetAsInt64 is GetAsInt64, reading from the source of r.
+func (r *Reader) GetAsInt64(key string, required bool, fallback int64, opts ...Option) (int64, error) {
+	return GetAsFrom(r, key, required, fallback, opts...)
+}
+
+// GetAsUint is GetAsUint, reading from the source of r.
+func (r *Reader) GetAsUint(key string, required bool, fallback uint, opts ...Option) (uint, error) {
+	return GetAsFrom(r, key, required, fallback, opts...)
+}
+
+// GetAsUint8 is GetAsUint8, reading from the source of r.
+func (r *Reader) GetAsUint8(key string, required bool, fallback uint8, opts ...Option) (uint8, error) {
+	return GetAsFrom(r, key, required, fallback, opts...)
+}
+
+// GetAsUint16 is GetAsUint16, reading from the source of r.
+func (r *Reader) GetAsUint16(key string, required bool, fallback uint16, opts ...Option) (uint16, error) {
+	return GetAsFrom(r, key, required, fallback, opts...)
+}
+
+// GetAsUint32 is GetAsUint32, reading from the source of r.
+func (r *Reader) GetAsUint32(key string, required bool, fallback uint32, opts ...Option) (uint32, error) {
+	return GetAsFrom(r, key, required, fallback, opts...)
+}
+
+// GetAsUint64 is GetAsUint64, reading from the source of r.
+func (r *Reader) GetAsUint64(key string, required bool, fallback uint64, opts ...Option) (uint64, error) {
+	return GetAsFrom(r, key, required, fallback, opts...)
+}
+
+// GetAsFloat64 is GetAsFloat64, reading from the source of r.
+func (r *Reader) GetAsFloat64(key string, required bool, fallback float64, opts ...Option) (float64, error) {
+	return GetAsFrom(r, key, required, fallback, opts...)
+}
+
+// GetAsBool is GetAsBool, reading from the source of r.
+func (r *Reader) GetAsBool(key string, required bool, fallback bool, opts ...Option) (bool, error) {
+	return GetAsFrom(r, key, required, fallback, opts...)
+}
+
+// GetAsDuration is GetAsDuration, reading from the source of r.
+func (r *Reader) GetAsDuration(key string, required bool, fallback time.Duration, opts ...Option) (time.Duration, error) {
+	return GetAsFrom(r, key, required, fallback, opts...)
+}
+
+// GetAsTime is GetAsTime, reading from the source of r.
+func (r *Reader) GetAsTime(key string, required bool, fallback time.Time, opts ...Option) (time.Time, error) {
+	return GetAsFrom(r, key, required, fallback, opts...)
+}
+
+// GetAsQuantity is GetAsQuantity, reading from the source of r.
+func (r *Reader) GetAsQuantity(key string, required bool, fallback int, opts ...Option) (int, error) {
+	return get(r, key, required, fallback, opts, func(value string, _ *options) (int, error) {
+		return parse.Quantity(value)
+	})
+}
+
+// GetAsFrom is GetAs, reading from the source of r. Go does not allow type parameters on methods, which is why this
+// is a function.
+func GetAsFrom[T any](r *Reader, key string, required bool, fallback T, opts ...Option) (T, error) {
+	return get(r, key, required, fallback, opts, parseAs[T])
+}
+
+// GetAsTypeFrom is GetAsType, reading from the source of r. Go does not allow type parameters on methods, which is
+// why this is a function.
+func GetAsTypeFrom[T any](r *Reader, key string, unmarshalTo *T, required bool, fallback T, opts ...Option) error {
+	value, err := get(r, key, required, fallback, opts, func(value string, o *options) (T, error) {
+		target := *unmarshalTo
+		if o.merge {
+			var err error
+			if target, err = copyJSON(fallback); err != nil {
+				return target, err
+			}
+		}
+		err := decodeValue(value, &target, o)
+		return target, err
+	})
+	var notSet *NotSetError
+	if errors.As(err, &notSet) {
+		return err
+	}
+	*unmarshalTo = value
+	return err
+}
+
+// NewLoader returns a Loader that reads from the source of r and applies opts to every lookup.
+func (r *Reader) NewLoader(opts ...Option) *Loader {
+	return &Loader{reader: r, opts: opts}
+}
+
+// Load is Load, reading from the source of r.
+func (r *Reader) Load(cfg any, opts ...Option) error {
+	return load(r.NewLoader(opts...), cfg)
+}
+
+// options returns the settings of r overridden by opts.
+func (r *Reader) options(opts []Option) *options {
+	if len(r.opts) == 0 {
+		return newOptions(opts)
+	}
+	return newOptions(append(append([]Option{}, r.opts...), opts...))
+}
+
+// lookup returns the raw value of key and where it came from. If key is not set but KEY_FILE is, the value is read
+// from the file named by KEY_FILE, following the convention of Docker and Kubernetes secrets. Empty values count as
+// not set if o asks for it, so an empty KEY next to KEY_FILE is not a conflict.
+func (r *Reader) lookup(key string, o *options) (string, location, bool, error) {
+	return lookupSource(r.source, key, o)
+}
+
+// lookupSource is lookup for source. The sources of a ChainSource are resolved one by one, so that KEY_FILE in one
+// source overrides KEY in a later one and vice versa; KEY and KEY_FILE only conflict within the same source.
+func lookupSource(source Source, key string, o *options) (string, location, bool, error) {
+	if chain, ok := source.(ChainSource); ok {
+		for _, s := range chain {
+			value, loc, set, err := lookupSource(s, key, o)
+			if err != nil || set {
+				return value, loc, set, err
+			}
+		}
+		return "", location{}, false, nil
+	}
+
+	value, set, err := source.Lookup(key)
+	if err != nil {
+		return "", location{}, false, fmt.Errorf("failed to read environment variable %s: %w", key, err)
+	}
+	set = set && !o.isEmpty(value)
+
+	fileKey := key + FileSuffix
+	path, fileSet, err := source.Lookup(fileKey)
+	if err != nil {
+		return "", location{}, false, fmt.Errorf("failed to read environment variable %s: %w", fileKey, err)
+	}
+	fileSet = fileSet && !o.isEmpty(path)
+	if !fileSet {
+		if !set {
+			return "", location{}, false, nil
+		}
+		return value, originOf(source, key), true, nil
+	}
+	if set {
+		return "", location{}, false, fmt.Errorf("environment variables %s and %s are both set: %w", key, fileKey, ErrFileConflict)
+	}
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return "", location{}, false, &FileError{Key: key, Path: path, Err: err}
+	}
+	return trimNewline(string(content)), location{origin: OriginFile, path: path}, true, nil
+}
+
+// lookupWith returns the raw value of key, or of the first of its aliases that is set, as configured by o. Empty
+// values count as not set if o asks for it.
+func (r *Reader) lookupWith(key string, o *options) (string, location, bool, error) {
+	value, loc, set, err := r.lookup(key, o)
+	if err != nil || (set && !o.isEmpty(value)) {
+		return value, loc, set, err
+	}
+	for _, alias := range o.aliases {
+		value, loc, set, err = r.lookup(alias.Key, o)
+		if err != nil {
+			return "", location{}, false, err
+		}
+		if set && !o.isEmpty(value) {
+			r.warnAlias(key, alias, o)
+			loc.alias = alias.Key
+			return value, loc, true, nil
+		}
+	}
+	return "", location{}, false, nil
+}
+
+// get implements the rules shared by all getters: if key is not set, fallback is returned, or a *NotSetError if
+// the variable is required. If the value cannot be parsed, fallback is returned together with a *ParseError, and
+// if it violates a validation rule, together with a *ValidationError. The Policy of the lookup may change this. The
+// outcome is recorded for Snapshot.
+func get[T any](r *Reader, key string, required bool, fallback T, opts []Option, parse func(string, *options) (T, error)) (T, error) {
+	o := r.options(opts)
+	o.register(key, typeNameOf(fallback), required, valueOf(fallback))
+	value, loc, err := resolve(r, key, required, fallback, o, parse)
+	zero, policyErr := o.applyPolicy(key, err)
+	if zero {
+		value = zeroOf(fallback)
+	}
+	r.record(key, loc, valueOf(value), err, o)
+	return value, policyErr
+}
+
+// resolve looks up and parses key for get.
+func resolve[T any](r *Reader, key string, required bool, fallback T, o *options, parse func(string, *options) (T, error)) (T, location, error) {
+	fromDefault := location{origin: OriginDefault}
+	value, loc, set, err := r.lookupWith(key, o)
+	if err != nil {
+		return fallback, fromDefault, err
+	}
+
+	// Check if the environment variable is set
+	if !set {
+		// If not required, return the fallback value
+		if !required {
+			return fallback, fromDefault, nil
+		}
+		// If required, return an error
+		return zeroOf(fallback), location{origin: OriginUnset}, &NotSetError{Key: key, Type: typeNameOf(fallback)}
+	}
+	if o.rejectsEmpty(required, value) {
+		return zeroOf(fallback), loc, &NotSetError{Key: key, Type: typeNameOf(fallback), Empty: true}
+	}
+
+	parsed, err := parse(value, o)
+	if err != nil {
+		return fallback, fromDefault, newParseError(key, value, typeNameOf(fallback), err, o)
+	}
+	if err = validate(key, value, valueOf(parsed), o); err != nil {
+		return fallback, fromDefault, err
+	}
+	return parsed, loc, nil
+}
@@ -0,0 +1,94 @@
+package env
+
+/*
+Copyright 2023 UMH Systems GmbH
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Source provides the raw values of variables.
+type Source interface {
+	// Lookup returns the value of key and whether it is set. An error is returned if the source could not be read.
+	Lookup(key string) (value string, ok bool, err error)
+}
+
+// OSSource reads variables from the environment of the process.
+type OSSource struct{}
+
+// Lookup returns the value of the environment variable key.
+func (OSSource) Lookup(key string) (string, bool, error) {
+	value, ok := os.LookupEnv(key)
+	return value, ok, nil
+}
+
+// MapSource reads variables from an in-memory map. It is mainly meant for tests, which can use it instead of
+// changing the environment of the process.
+type MapSource map[string]string
+
+// Lookup returns the value stored for key.
+func (m MapSource) Lookup(key string) (string, bool, error) {
+	value, ok := m[key]
+	return value, ok, nil
+}
+
+// DirSource reads variables from a directory that holds one file per variable, named after the variable. This is
+// the layout of ConfigMaps and Secrets mounted into a Kubernetes pod. A single trailing newline is removed from the
+// file content.
+type DirSource struct {
+	// Path is the directory containing the files.
+	Path string
+}
+
+// Lookup returns the content of the file named key, or reports it as not set if there is no such file.
+func (d DirSource) Lookup(key string) (string, bool, error) {
+	if key == "" || filepath.Base(key) != key {
+		return "", false, nil
+	}
+	content, err := os.ReadFile(filepath.Join(d.Path, key))
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return "", false, nil
+		}
+		return "", false, err
+	}
+	return trimNewline(string(content)), true, nil
+}
+
+// ChainSource looks up variables in several sources, in order. The first source that has a variable wins. A Reader
+// applies the KEY_FILE convention to each source on its own, so the first source that has KEY or KEY_FILE wins.
+type ChainSource []Source
+
+// Lookup returns the value of key from the first source that has it set.
+func (c ChainSource) Lookup(key string) (string, bool, error) {
+	for _, source := range c {
+		value, ok, err := source.Lookup(key)
+		if err != nil || ok {
+			return value, ok, err
+		}
+	}
+	return "", false, nil
+}
+
+// trimNewline removes a single trailing line break, as written by most editors and by `echo`.
+func trimNewline(s string) string {
+	s = strings.TrimSuffix(s, "\n")
+	return strings.TrimSuffix(s, "\r")
+}
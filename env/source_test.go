@@ -0,0 +1,161 @@
+package env
+
+/*
+Copyright 2023 UMH Systems GmbH
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// TestSources tests the Lookup method of every Source implementation
+func TestSources(t *testing.T) {
+	t.Setenv("SOURCE_OS_VAR", "from-os")
+
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "SOURCE_DIR_VAR"), []byte("from-dir\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	mapSource := MapSource{"SOURCE_MAP_VAR": "from-map", "SOURCE_DIR_VAR": "shadowed"}
+	tests := []struct {
+		source Source
+		name   string
+		key    string
+		want   string
+		wantOk bool
+	}{
+		{name: "Case 1: OS variable exists", source: OSSource{}, key: "SOURCE_OS_VAR", want: "from-os", wantOk: true},
+		{name: "Case 2: OS variable does not exist", source: OSSource{}, key: "SOURCE_NONEXISTENT_VAR"},
+		{name: "Case 3: Map variable exists", source: mapSource, key: "SOURCE_MAP_VAR", want: "from-map", wantOk: true},
+		{name: "Case 4: Map variable does not exist", source: mapSource, key: "SOURCE_NONEXISTENT_VAR"},
+		{name: "Case 5: Dir file exists, trailing newline is trimmed", source: DirSource{Path: dir}, key: "SOURCE_DIR_VAR", want: "from-dir", wantOk: true},
+		{name: "Case 6: Dir file does not exist", source: DirSource{Path: dir}, key: "SOURCE_NONEXISTENT_VAR"},
+		{name: "Case 7: Dir key escapes the directory", source: DirSource{Path: dir}, key: "../SOURCE_DIR_VAR"},
+		{name: "Case 8: Chain returns the first source that has the variable", source: ChainSource{DirSource{Path: dir}, mapSource}, key: "SOURCE_DIR_VAR", want: "from-dir", wantOk: true},
+		{name: "Case 9: Chain falls through to later sources", source: ChainSource{OSSource{}, mapSource}, key: "SOURCE_MAP_VAR", want: "from-map", wantOk: true},
+		{name: "Case 10: Chain variable does not exist", source: ChainSource{OSSource{}, mapSource}, key: "SOURCE_NONEXISTENT_VAR"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok, err := tt.source.Lookup(tt.key)
+			if err != nil {
+				t.Fatalf("Lookup() error = %v", err)
+			}
+			if got != tt.want || ok != tt.wantOk {
+				t.Errorf("Lookup() = %q, %v, want %q, %v", got, ok, tt.want, tt.wantOk)
+			}
+		})
+	}
+}
+
+// TestReader tests that a Reader applies the getter rules to its own source
+func TestReader(t *testing.T) {
+	t.Parallel()
+
+	r := NewReader(MapSource{
+		"NAME":   "umh",
+		"PORT":   "8080",
+		"BUFFER": "1024",
+		"RATIO":  "0.5",
+		"DEBUG":  "true",
+		"TOPICS": `["a","b"]`,
+		"WRONG":  "wrong",
+	})
+
+	if got, err := r.GetAsString("NAME", true, ""); err != nil || got != "umh" {
+		t.Errorf("GetAsString() = %q, %v", got, err)
+	}
+	if got, err := r.GetAsInt("PORT", true, 0); err != nil || got != 8080 {
+		t.Errorf("GetAsInt() = %d, %v", got, err)
+	}
+	if got, err := r.GetAsUint64("BUFFER", true, 0); err != nil || got != 1024 {
+		t.Errorf("GetAsUint64() = %d, %v", got, err)
+	}
+	if got, err := r.GetAsFloat64("RATIO", true, 0); err != nil || got != 0.5 {
+		t.Errorf("GetAsFloat64() = %v, %v", got, err)
+	}
+	if got, err := r.GetAsBool("DEBUG", true, false); err != nil || !got {
+		t.Errorf("GetAsBool() = %v, %v", got, err)
+	}
+	if got, err := r.GetAsInt("WRONG", false, 42); err == nil || got != 42 {
+		t.Errorf("GetAsInt() = %d, %v, want fallback and error", got, err)
+	}
+	if _, err := r.GetAsString("NONEXISTENT", true, ""); err == nil {
+		t.Error("GetAsString() expected error for a required variable")
+	}
+
+	var topics []string
+	if err := GetAsTypeFrom(r, "TOPICS", &topics, true, nil); err != nil || !reflect.DeepEqual(topics, []string{"a", "b"}) {
+		t.Errorf("GetAsTypeFrom() = %v, %v", topics, err)
+	}
+
+	var cfg struct {
+		Name string `env:"NAME"`
+		Port int    `env:"PORT"`
+	}
+	if err := r.Load(&cfg); err != nil || cfg.Name != "umh" || cfg.Port != 8080 {
+		t.Errorf("Load() = %+v, %v", cfg, err)
+	}
+}
+
+// TestReaderFileSuffix tests that values are read from the file named by KEY_FILE
+func TestReaderFileSuffix(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	secret := filepath.Join(dir, "password")
+	if err := os.WriteFile(secret, []byte("s3cr3t\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	port := filepath.Join(dir, "port")
+	if err := os.WriteFile(port, []byte("8080"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	missing := filepath.Join(dir, "missing")
+
+	r := NewReader(MapSource{
+		"PASSWORD_FILE": secret,
+		"PORT_FILE":     port,
+		"MISSING_FILE":  missing,
+		"BOTH":          "value",
+		"BOTH_FILE":     secret,
+		"TOPICS_FILE":   missing,
+	})
+
+	if got, err := r.GetAsString("PASSWORD", true, ""); err != nil || got != "s3cr3t" {
+		t.Errorf("GetAsString() = %q, %v, want file content", got, err)
+	}
+	if got, err := r.GetAsInt("PORT", true, 0); err != nil || got != 8080 {
+		t.Errorf("GetAsInt() = %d, %v, want file content", got, err)
+	}
+
+	var fileErr *FileError
+	if got, err := r.GetAsString("MISSING", false, "fallback"); !errors.As(err, &fileErr) || fileErr.Path != missing || got != "fallback" {
+		t.Errorf("GetAsString() = %q, %v, want *FileError for %s", got, err, missing)
+	}
+	if _, err := r.GetAsString("BOTH", false, ""); !errors.Is(err, ErrFileConflict) {
+		t.Errorf("GetAsString() error = %v, want ErrFileConflict", err)
+	}
+	var topics []string
+	if err := GetAsTypeFrom(r, "TOPICS", &topics, false, nil); !errors.As(err, &fileErr) {
+		t.Errorf("GetAsTypeFrom() error = %v, want *FileError", err)
+	}
+}
@@ -0,0 +1,214 @@
+package env
+
+/*
+Copyright 2023 UMH Systems GmbH
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+import (
+	"crypto"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrKeyMismatch is matched by errors.Is when a private key does not belong to its certificate.
+	ErrKeyMismatch = errors.New("private key does not match the certificate")
+	// ErrCertExpired is matched by errors.Is when a certificate is past its expiry date.
+	ErrCertExpired = errors.New("certificate has expired")
+	// ErrCertNotYetValid is matched by errors.Is when a certificate is not valid yet.
+	ErrCertNotYetValid = errors.New("certificate is not valid yet")
+)
+
+// tlsVersions maps the accepted values of PREFIX_MIN_VERSION to TLS versions.
+var tlsVersions = map[string]uint16{
+	"1.0": tls.VersionTLS10,
+	"1.1": tls.VersionTLS11,
+	"1.2": tls.VersionTLS12,
+	"1.3": tls.VersionTLS13,
+}
+
+// TLSError is returned when the TLS material in a variable is unusable.
+type TLSError struct {
+	// Err describes the problem, e.g. ErrCertExpired.
+	Err error
+	// Key is the name of the variable holding the material.
+	Key string
+}
+
+func (e *TLSError) Error() string {
+	return fmt.Sprintf("environment variable %s: %v", e.Key, e.Err)
+}
+
+func (e *TLSError) Unwrap() error {
+	return e.Err
+}
+
+// TLSConfig builds a *tls.Config from the variables that start with prefix, e.g. "KAFKA_TLS":
+//
+//	KAFKA_TLS_CA           CA certificates that verify the peer, in PEM; the system pool is used if not set
+//	KAFKA_TLS_CERT         certificate chain presented to the peer, in PEM
+//	KAFKA_TLS_KEY          private key of the certificate, in PEM; required if KAFKA_TLS_CERT is set
+//	KAFKA_TLS_MIN_VERSION  minimum TLS version: 1.0, 1.1, 1.2 or 1.3; the default is 1.2
+//	KAFKA_TLS_INSECURE     skips the verification of the peer; the default is false
+//	KAFKA_TLS_SERVER_NAME  name used to verify the certificate of the peer; the default is the host name dialed
+//
+// As for every lookup, the PEM values can be read from files instead, e.g. KAFKA_TLS_CA_FILE. The key is treated
+// as sensitive. The certificates are checked before they are used: all errors of the lookups are returned together
+// as Errors, and unusable material as *TLSError, matching ErrKeyMismatch, ErrCertExpired or ErrCertNotYetValid
+// where applicable.
+func TLSConfig(prefix string, opts ...Option) (*tls.Config, error) {
+	return defaultReader.TLSConfig(prefix, opts...)
+}
+
+// TLSConfig is TLSConfig, reading from the source of r.
+func (r *Reader) TLSConfig(prefix string, opts ...Option) (*tls.Config, error) {
+	prefix = strings.TrimSuffix(prefix, "_") + "_"
+	l := r.NewLoader(opts...)
+	ca := l.String(prefix+"CA", false, "", Description("CA certificates that verify the peer, in PEM"))
+	cert := l.String(prefix+"CERT", false, "", Description("Certificate chain presented to the peer, in PEM"))
+	key := l.String(prefix+"KEY", cert != "", "", Sensitive(), Description("Private key of the certificate, in PEM"))
+	minVersion := l.String(prefix+"MIN_VERSION", false, "1.2", OneOf("1.0", "1.1", "1.2", "1.3"),
+		Description("Minimum TLS version"))
+	insecure := l.Bool(prefix+"INSECURE", false, false, Description("Skips the verification of the peer"))
+	serverName := l.String(prefix+"SERVER_NAME", false, "", Description("Name used to verify the certificate of the peer"))
+	if err := l.Err(); err != nil {
+		return nil, err
+	}
+
+	config := &tls.Config{
+		MinVersion: tlsVersions[minVersion],
+		ServerName: serverName,
+		// Skipping the verification must be asked for explicitly
+		InsecureSkipVerify: insecure, //nolint:gosec
+	}
+	now := time.Now()
+
+	if ca != "" {
+		certs, err := parseCertificates(ca, now)
+		if err != nil {
+			return nil, &TLSError{Key: prefix + "CA", Err: err}
+		}
+		config.RootCAs = x509.NewCertPool()
+		for _, c := range certs {
+			config.RootCAs.AddCert(c)
+		}
+	}
+
+	if cert == "" && key != "" {
+		return nil, &TLSError{Key: prefix + "KEY", Err: fmt.Errorf("%sCERT is not set", prefix)}
+	}
+	if cert != "" {
+		certificate, err := keyPair(prefix, cert, key, now)
+		if err != nil {
+			return nil, err
+		}
+		config.Certificates = []tls.Certificate{certificate}
+	}
+	return config, nil
+}
+
+// keyPair parses a certificate chain and its private key and checks that they belong together.
+func keyPair(prefix, certPEM, keyPEM string, now time.Time) (tls.Certificate, error) {
+	certs, err := parseCertificates(certPEM, now)
+	if err != nil {
+		return tls.Certificate{}, &TLSError{Key: prefix + "CERT", Err: err}
+	}
+	key, err := parsePrivateKey(keyPEM)
+	if err != nil {
+		return tls.Certificate{}, &TLSError{Key: prefix + "KEY", Err: err}
+	}
+
+	leaf := certs[0]
+	public, ok := key.(interface{ Public() crypto.PublicKey })
+	if !ok {
+		return tls.Certificate{}, &TLSError{Key: prefix + "KEY", Err: fmt.Errorf("unsupported private key type %T", key)}
+	}
+	equal, ok := public.Public().(interface{ Equal(crypto.PublicKey) bool })
+	if !ok || !equal.Equal(leaf.PublicKey) {
+		return tls.Certificate{}, &TLSError{Key: prefix + "KEY", Err: fmt.Errorf("%w %q", ErrKeyMismatch, leaf.Subject.String())}
+	}
+
+	certificate := tls.Certificate{PrivateKey: key, Leaf: leaf}
+	for _, c := range certs {
+		certificate.Certificate = append(certificate.Certificate, c.Raw)
+	}
+	return certificate, nil
+}
+
+// parseCertificates parses every certificate in a PEM document and checks that it is valid at now.
+func parseCertificates(data string, now time.Time) ([]*x509.Certificate, error) {
+	var certs []*x509.Certificate
+	rest := []byte(data)
+	for {
+		var block *pem.Block
+		block, rest = pem.Decode(rest)
+		if block == nil {
+			break
+		}
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+		c, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("certificate %d: %w", len(certs)+1, err)
+		}
+		switch {
+		case now.After(c.NotAfter):
+			return nil, fmt.Errorf("%w: %s expired at %s", ErrCertExpired, describeCertificate(c), c.NotAfter.Format(time.RFC3339))
+		case now.Before(c.NotBefore):
+			return nil, fmt.Errorf("%w: %s is valid from %s", ErrCertNotYetValid, describeCertificate(c), c.NotBefore.Format(time.RFC3339))
+		}
+		certs = append(certs, c)
+	}
+	if len(certs) == 0 {
+		return nil, errors.New("no PEM encoded certificate found")
+	}
+	return certs, nil
+}
+
+// parsePrivateKey parses the first private key in a PEM document, in PKCS #8, PKCS #1 or SEC 1 form.
+func parsePrivateKey(data string) (crypto.PrivateKey, error) {
+	rest := []byte(data)
+	for {
+		var block *pem.Block
+		block, rest = pem.Decode(rest)
+		if block == nil {
+			return nil, errors.New("no PEM encoded private key found")
+		}
+		if !strings.HasSuffix(block.Type, "PRIVATE KEY") {
+			continue
+		}
+		if key, err := x509.ParsePKCS8PrivateKey(block.Bytes); err == nil {
+			return key, nil
+		}
+		if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+			return key, nil
+		}
+		if key, err := x509.ParseECPrivateKey(block.Bytes); err == nil {
+			return key, nil
+		}
+		return nil, fmt.Errorf("cannot parse %s", strings.ToLower(block.Type))
+	}
+}
+
+// describeCertificate names a certificate in error messages.
+func describeCertificate(c *x509.Certificate) string {
+	return fmt.Sprintf("certificate %q", c.Subject.String())
+}
@@ -0,0 +1,225 @@
+package env
+
+/*
+Copyright 2023 UMH Systems GmbH
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"io/fs"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// tlsTestCert is a certificate generated for a test, in PEM.
+type tlsTestCert struct {
+	cert *x509.Certificate
+	key  crypto.Signer
+	// CertPEM is the certificate and KeyPEM its private key.
+	CertPEM string
+	KeyPEM  string
+}
+
+// newTLSTestCert creates a certificate valid between notBefore and notAfter. It is signed by parent, or self-signed
+// if parent is nil. rsaKey selects an RSA key in PKCS #1 form instead of an ECDSA key in PKCS #8 form.
+func newTLSTestCert(t *testing.T, name string, parent *tlsTestCert, notBefore, notAfter time.Time, rsaKey bool) *tlsTestCert {
+	t.Helper()
+
+	var key crypto.Signer
+	var keyBlock *pem.Block
+	if rsaKey {
+		rsaPriv, err := rsa.GenerateKey(rand.Reader, 2048)
+		if err != nil {
+			t.Fatal(err)
+		}
+		key, keyBlock = rsaPriv, &pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(rsaPriv)}
+	} else {
+		ecPriv, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		if err != nil {
+			t.Fatal(err)
+		}
+		der, err := x509.MarshalPKCS8PrivateKey(ecPriv)
+		if err != nil {
+			t.Fatal(err)
+		}
+		key, keyBlock = ecPriv, &pem.Block{Type: "PRIVATE KEY", Bytes: der}
+	}
+
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(time.Now().UnixNano()),
+		Subject:               pkix.Name{CommonName: name},
+		DNSNames:              []string{name},
+		NotBefore:             notBefore,
+		NotAfter:              notAfter,
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  parent == nil,
+	}
+	signerCert, signerKey := template, key
+	if parent != nil {
+		signerCert, signerKey = parent.cert, parent.key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, signerCert, key.Public(), signerKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &tlsTestCert{
+		cert:    cert,
+		key:     key,
+		CertPEM: string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})),
+		KeyPEM:  string(pem.EncodeToMemory(keyBlock)),
+	}
+}
+
+// TestTLSConfig tests building TLS configurations and the errors for unusable material
+func TestTLSConfig(t *testing.T) {
+	t.Parallel()
+
+	now := time.Now()
+	ca := newTLSTestCert(t, "umh-ca", nil, now.Add(-time.Hour), now.Add(time.Hour), false)
+	client := newTLSTestCert(t, "kafka-client", ca, now.Add(-time.Hour), now.Add(time.Hour), false)
+	rsaClient := newTLSTestCert(t, "rsa-client", ca, now.Add(-time.Hour), now.Add(time.Hour), true)
+	other := newTLSTestCert(t, "other", ca, now.Add(-time.Hour), now.Add(time.Hour), false)
+	expired := newTLSTestCert(t, "expired", ca, now.Add(-2*time.Hour), now.Add(-time.Hour), false)
+	future := newTLSTestCert(t, "future", ca, now.Add(time.Hour), now.Add(2*time.Hour), false)
+
+	dir := t.TempDir()
+	caFile := filepath.Join(dir, "ca.pem")
+	keyFile := filepath.Join(dir, "client.key")
+	for path, content := range map[string]string{caFile: ca.CertPEM, keyFile: client.KeyPEM} {
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		wantErr     error
+		source      MapSource
+		name        string
+		wantErrText string
+		wantVersion uint16
+		wantCerts   int
+		wantRoots   bool
+		insecure    bool
+	}{
+		{name: "Case 1: Nothing set", source: MapSource{}, wantVersion: tls.VersionTLS12},
+		{
+			name:        "Case 2: Inline PEM",
+			source:      MapSource{"TLS_CA": ca.CertPEM, "TLS_CERT": client.CertPEM, "TLS_KEY": client.KeyPEM, "TLS_MIN_VERSION": "1.3", "TLS_SERVER_NAME": "kafka"},
+			wantVersion: tls.VersionTLS13, wantCerts: 1, wantRoots: true,
+		},
+		{
+			name:        "Case 3: Files",
+			source:      MapSource{"TLS_CA_FILE": caFile, "TLS_CERT": client.CertPEM, "TLS_KEY_FILE": keyFile},
+			wantVersion: tls.VersionTLS12, wantCerts: 1, wantRoots: true,
+		},
+		{
+			name:        "Case 4: RSA key in PKCS #1 form and a chain",
+			source:      MapSource{"TLS_CERT": rsaClient.CertPEM + ca.CertPEM, "TLS_KEY": rsaClient.KeyPEM, "TLS_INSECURE": "true"},
+			wantVersion: tls.VersionTLS12, wantCerts: 1, insecure: true,
+		},
+		{name: "Case 5: Key does not match", source: MapSource{"TLS_CERT": client.CertPEM, "TLS_KEY": other.KeyPEM}, wantErr: ErrKeyMismatch, wantErrText: "TLS_KEY"},
+		{name: "Case 6: Expired certificate", source: MapSource{"TLS_CERT": expired.CertPEM, "TLS_KEY": expired.KeyPEM}, wantErr: ErrCertExpired, wantErrText: `certificate "CN=expired" expired at`},
+		{name: "Case 7: Expired CA", source: MapSource{"TLS_CA": expired.CertPEM}, wantErr: ErrCertExpired, wantErrText: "TLS_CA"},
+		{name: "Case 8: Certificate not valid yet", source: MapSource{"TLS_CERT": future.CertPEM, "TLS_KEY": future.KeyPEM}, wantErr: ErrCertNotYetValid},
+		{name: "Case 9: Unreadable file", source: MapSource{"TLS_CA_FILE": filepath.Join(dir, "missing.pem")}, wantErr: fs.ErrNotExist, wantErrText: "missing.pem"},
+		{name: "Case 10: Certificate without key", source: MapSource{"TLS_CERT": client.CertPEM}, wantErr: ErrNotSet, wantErrText: "TLS_KEY"},
+		{name: "Case 11: Key without certificate", source: MapSource{"TLS_KEY": client.KeyPEM}, wantErrText: "TLS_CERT is not set"},
+		{name: "Case 12: Not PEM", source: MapSource{"TLS_CA": "not a certificate"}, wantErrText: "no PEM encoded certificate found"},
+		{name: "Case 13: Invalid version", source: MapSource{"TLS_MIN_VERSION": "1.4"}, wantErrText: "TLS_MIN_VERSION"},
+		{name: "Case 14: Key is redacted", source: MapSource{"TLS_CERT": client.CertPEM, "TLS_KEY": "secret"}, wantErrText: "no PEM encoded private key found"},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			config, err := NewReader(tt.source, WithRegistry(NewRegistry())).TLSConfig("TLS_")
+			if tt.wantErr == nil && tt.wantErrText == "" {
+				if err != nil {
+					t.Fatalf("TLSConfig() error = %v", err)
+				}
+				if config.MinVersion != tt.wantVersion || len(config.Certificates) != tt.wantCerts || (config.RootCAs != nil) != tt.wantRoots || config.InsecureSkipVerify != tt.insecure {
+					t.Errorf("TLSConfig() = %+v", config)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatalf("TLSConfig() error = nil, want %v", tt.wantErr)
+			}
+			if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+				t.Errorf("TLSConfig() error = %v, want %v", err, tt.wantErr)
+			}
+			if !strings.Contains(err.Error(), tt.wantErrText) || strings.Contains(err.Error(), "secret") {
+				t.Errorf("TLSConfig() error = %v, want it to mention %q", err, tt.wantErrText)
+			}
+		})
+	}
+}
+
+// TestTLSConfigHandshake tests that the built configurations can talk to each other
+func TestTLSConfigHandshake(t *testing.T) {
+	t.Parallel()
+
+	now := time.Now()
+	ca := newTLSTestCert(t, "umh-ca", nil, now.Add(-time.Hour), now.Add(time.Hour), false)
+	server := newTLSTestCert(t, "kafka", ca, now.Add(-time.Hour), now.Add(time.Hour), false)
+
+	serverConfig, err := NewReader(MapSource{"SERVER_TLS_CERT": server.CertPEM, "SERVER_TLS_KEY": server.KeyPEM}, WithRegistry(NewRegistry())).TLSConfig("SERVER_TLS")
+	if err != nil {
+		t.Fatal(err)
+	}
+	clientConfig, err := NewReader(MapSource{"CLIENT_TLS_CA": ca.CertPEM, "CLIENT_TLS_SERVER_NAME": "kafka"}, WithRegistry(NewRegistry())).TLSConfig("CLIENT_TLS")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	listener, err := tls.Listen("tcp", "127.0.0.1:0", serverConfig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	go func() {
+		conn, err := listener.Accept()
+		if err == nil {
+			_ = conn.(*tls.Conn).Handshake()
+			_ = conn.Close()
+		}
+	}()
+
+	conn, err := tls.Dial("tcp", listener.Addr().String(), clientConfig)
+	if err != nil {
+		t.Fatalf("Dial() error = %v", err)
+	}
+	_ = conn.Close()
+}
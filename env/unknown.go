@@ -0,0 +1,238 @@
+package env
+
+/*
+Copyright 2023 UMH Systems GmbH
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"sort"
+	"strings"
+)
+
+// Lister is implemented by sources that can list the variables they have set, which CheckUnknown needs.
+type Lister interface {
+	// Keys returns the names of all variables that are set.
+	Keys() ([]string, error)
+}
+
+// UnknownVar is a variable that is set, but not used by any registered lookup.
+type UnknownVar struct {
+	// Key is the name of the variable.
+	Key string
+	// Suggestion is the closest registered name, or empty if none is close enough to be a likely typo.
+	Suggestion string
+}
+
+func (u UnknownVar) String() string {
+	if u.Suggestion == "" {
+		return u.Key
+	}
+	return fmt.Sprintf("%s (did you mean %s?)", u.Key, u.Suggestion)
+}
+
+// UnknownError is returned by CheckUnknown with PolicyStrict or PolicyRequiredStrict.
+type UnknownError struct {
+	// Prefix is the prefix that was checked.
+	Prefix string
+	// Vars are the unknown variables, sorted by key.
+	Vars []UnknownVar
+}
+
+func (e *UnknownError) Error() string {
+	names := make([]string, len(e.Vars))
+	for i, u := range e.Vars {
+		names[i] = u.String()
+	}
+	return fmt.Sprintf("unknown environment variables with prefix %s: %s", e.Prefix, strings.Join(names, ", "))
+}
+
+// CheckUnknown reports the variables of the environment that start with prefix, e.g. "UMH_", but are not used by
+// any lookup recorded in the registry, together with the closest registered name. It must be called after the
+// configuration has been loaded, so that every lookup has been registered. Prefixes are compared case-insensitively.
+//
+// Each unknown variable is logged as a warning. With PolicyStrict or PolicyRequiredStrict, an *UnknownError is
+// returned instead. The registry is DefaultRegistry unless WithRegistry is given.
+func CheckUnknown(prefix string, opts ...Option) ([]UnknownVar, error) {
+	return defaultReader.CheckUnknown(prefix, opts...)
+}
+
+// CheckUnknown is CheckUnknown, listing the variables of the source of r, which must implement Lister.
+func (r *Reader) CheckUnknown(prefix string, opts ...Option) ([]UnknownVar, error) {
+	o := r.options(opts)
+	lister, ok := r.source.(Lister)
+	if !ok {
+		return nil, fmt.Errorf("source %T cannot list its variables", r.source)
+	}
+	keys, err := lister.Keys()
+	if err != nil {
+		return nil, err
+	}
+	reg := o.registry
+	if reg == nil {
+		reg = DefaultRegistry
+	}
+
+	known := make(map[string]bool)
+	var candidates []string
+	for _, v := range reg.Vars() {
+		candidates = append(candidates, v.Key)
+		for _, name := range append(append([]string{v.Key}, v.Aliases...), v.Deprecated...) {
+			known[name] = true
+			known[name+FileSuffix] = true
+		}
+	}
+
+	var unknown []UnknownVar
+	for _, key := range keys {
+		if known[key] || !strings.HasPrefix(strings.ToUpper(key), strings.ToUpper(prefix)) {
+			continue
+		}
+		unknown = append(unknown, UnknownVar{Key: key, Suggestion: suggest(key, candidates)})
+	}
+	sort.Slice(unknown, func(i, j int) bool {
+		return unknown[i].Key < unknown[j].Key
+	})
+	if len(unknown) == 0 {
+		return nil, nil
+	}
+
+	if o.policy == PolicyStrict || o.policy == PolicyRequiredStrict {
+		return unknown, &UnknownError{Prefix: prefix, Vars: unknown}
+	}
+	for _, u := range unknown {
+		if u.Suggestion == "" {
+			o.warn("Unknown environment variable", "key", u.Key)
+		} else {
+			o.warn("Unknown environment variable", "key", u.Key, "suggestion", u.Suggestion)
+		}
+	}
+	return unknown, nil
+}
+
+// suggest returns the candidate closest to key by edit distance, ignoring case and a KEY_FILE suffix, or an empty
+// string if the closest one differs in more than a third of the characters.
+func suggest(key string, candidates []string) string {
+	key = strings.TrimSuffix(strings.ToUpper(key), FileSuffix)
+	best, bestDistance := "", len(key)/3+1
+	for _, candidate := range candidates {
+		if d := levenshtein(key, strings.ToUpper(candidate)); d < bestDistance {
+			best, bestDistance = candidate, d
+		}
+	}
+	return best
+}
+
+// levenshtein returns the number of single-character insertions, deletions and substitutions that turn a into b.
+func levenshtein(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	prev := make([]int, len(rb)+1)
+	curr := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		curr[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			curr[j] = minInt(minInt(prev[j]+1, curr[j-1]+1), prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(rb)]
+}
+
+// minInt returns the smaller of a and b.
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+// Keys returns the names of all variables in the environment of the process.
+func (OSSource) Keys() ([]string, error) {
+	environ := os.Environ()
+	keys := make([]string, 0, len(environ))
+	for _, kv := range environ {
+		if key, _, ok := strings.Cut(kv, "="); ok && key != "" {
+			keys = append(keys, key)
+		}
+	}
+	return keys, nil
+}
+
+// Keys returns the keys of the map.
+func (m MapSource) Keys() ([]string, error) {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	return keys, nil
+}
+
+// Keys returns the names of the regular files in the directory, skipping hidden ones such as the ..data link of
+// Kubernetes volumes. A missing directory has no variables.
+func (d DirSource) Keys() ([]string, error) {
+	entries, err := os.ReadDir(d.Path)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var keys []string
+	for _, entry := range entries {
+		if strings.HasPrefix(entry.Name(), ".") || entry.IsDir() {
+			continue
+		}
+		keys = append(keys, entry.Name())
+	}
+	return keys, nil
+}
+
+// Keys returns the variables of all sources that implement Lister, without duplicates.
+func (c ChainSource) Keys() ([]string, error) {
+	seen := make(map[string]bool)
+	var keys []string
+	for _, source := range c {
+		lister, ok := source.(Lister)
+		if !ok {
+			continue
+		}
+		names, err := lister.Keys()
+		if err != nil {
+			return nil, err
+		}
+		for _, name := range names {
+			if !seen[name] {
+				seen[name] = true
+				keys = append(keys, name)
+			}
+		}
+	}
+	return keys, nil
+}
+
+// Keys returns the variables of the file.
+func (c ConfigFileSource) Keys() ([]string, error) {
+	return c.Values.Keys()
+}
@@ -0,0 +1,156 @@
+package env
+
+/*
+Copyright 2023 UMH Systems GmbH
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+// TestCheckUnknown tests that unused variables under a prefix are reported with the closest registered name
+func TestCheckUnknown(t *testing.T) {
+	t.Parallel()
+
+	secret := filepath.Join(t.TempDir(), "password")
+	if err := os.WriteFile(secret, []byte("s3cr3t"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	source := MapSource{
+		"UMH_KAFKA_BROKER":      "kafka:9092",
+		"UMH_KAFKA_BROKERS":     "kafka:9092",
+		"UMH_MQTT_URL":          "tcp://mqtt:1883",
+		"UMH_PASSWORD_FILE":     secret,
+		"UMH_PASWORD_FILE":      secret,
+		"umh_log_level":         "DEBUG",
+		"UMH_SOMETHING_ELSE":    "x",
+		"OTHER_KAFKA_BROKER":    "kafka:9092",
+		"UMH_MQTT_BROKER_URL":   "tcp://mqtt:1883",
+		"UMH_DEPRECATED_BROKER": "x",
+	}
+	reg := NewRegistry()
+	r := NewReader(source, WithRegistry(reg), WithLogger(zap.NewNop().Sugar()))
+	var cfg struct {
+		Brokers  string `env:"UMH_KAFKA_BROKERS"`
+		MQTT     string `env:"UMH_MQTT_BROKER" deprecated:"UMH_MQTT_URL"`
+		Password string `env:"UMH_PASSWORD,sensitive"`
+		LogLevel string `env:"UMH_LOG_LEVEL"`
+	}
+	if err := r.Load(&cfg); err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+
+	want := []UnknownVar{
+		{Key: "UMH_DEPRECATED_BROKER"},
+		{Key: "UMH_KAFKA_BROKER", Suggestion: "UMH_KAFKA_BROKERS"},
+		{Key: "UMH_MQTT_BROKER_URL", Suggestion: "UMH_MQTT_BROKER"},
+		{Key: "UMH_PASWORD_FILE", Suggestion: "UMH_PASSWORD"},
+		{Key: "UMH_SOMETHING_ELSE"},
+		{Key: "umh_log_level", Suggestion: "UMH_LOG_LEVEL"},
+	}
+
+	core, logs := observer.New(zapcore.WarnLevel)
+	got, err := r.CheckUnknown("UMH_", WithLogger(zap.New(core).Sugar()))
+	if err != nil {
+		t.Fatalf("CheckUnknown() error = %v", err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("CheckUnknown() = %+v, want %+v", got, want)
+	}
+	if logs.Len() != len(want) {
+		t.Errorf("logged %d warnings, want %d", logs.Len(), len(want))
+	} else if fields := logs.All()[1].ContextMap(); fields["key"] != "UMH_KAFKA_BROKER" || fields["suggestion"] != "UMH_KAFKA_BROKERS" {
+		t.Errorf("warning fields = %v", fields)
+	}
+
+	got, err = r.CheckUnknown("UMH_", WithPolicy(PolicyStrict))
+	var unknownErr *UnknownError
+	if !errors.As(err, &unknownErr) || !reflect.DeepEqual(unknownErr.Vars, want) || !reflect.DeepEqual(got, want) {
+		t.Fatalf("CheckUnknown() = %+v, %v, want *UnknownError", got, err)
+	}
+	if msg := err.Error(); msg != "unknown environment variables with prefix UMH_: UMH_DEPRECATED_BROKER, UMH_KAFKA_BROKER (did you mean UMH_KAFKA_BROKERS?), UMH_MQTT_BROKER_URL (did you mean UMH_MQTT_BROKER?), UMH_PASWORD_FILE (did you mean UMH_PASSWORD?), UMH_SOMETHING_ELSE, umh_log_level (did you mean UMH_LOG_LEVEL?)" {
+		t.Errorf("Error() = %s", msg)
+	}
+
+	if got, err = r.CheckUnknown("OTHER_NONEXISTENT_"); err != nil || got != nil {
+		t.Errorf("CheckUnknown() = %+v, %v, want nothing", got, err)
+	}
+}
+
+// TestSuggest tests the suggestions for misspelled names
+func TestSuggest(t *testing.T) {
+	t.Parallel()
+
+	candidates := []string{"UMH_KAFKA_BROKERS", "UMH_KAFKA_TOPICS", "UMH_PORT"}
+	tests := []struct {
+		name string
+		key  string
+		want string
+	}{
+		{name: "Case 1: Missing letter", key: "UMH_KAFKA_BROKER", want: "UMH_KAFKA_BROKERS"},
+		{name: "Case 2: Swapped letters", key: "UMH_KAFKA_TOPCIS", want: "UMH_KAFKA_TOPICS"},
+		{name: "Case 3: Wrong case", key: "umh_port", want: "UMH_PORT"},
+		{name: "Case 4: Too different", key: "UMH_MQTT_URL", want: ""},
+		{name: "Case 5: Names sharing only the prefix", key: "UMH_DEBUG", want: ""},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			if got := suggest(tt.key, candidates); got != tt.want {
+				t.Errorf("suggest(%q) = %q, want %q", tt.key, got, tt.want)
+			}
+		})
+	}
+}
+
+// TestKeys tests listing the variables of sources
+func TestKeys(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	for _, name := range []string{"DIR_KEY", ".hidden"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte("x"), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.Mkdir(filepath.Join(dir, "..data"), 0o700); err != nil {
+		t.Fatal(err)
+	}
+
+	keys, err := ChainSource{MapSource{"MAP_KEY": "x", "DIR_KEY": "y"}, DirSource{Path: dir}, DirSource{Path: filepath.Join(dir, "missing")}}.Keys()
+	if err != nil {
+		t.Fatalf("Keys() error = %v", err)
+	}
+	seen := map[string]int{}
+	for _, key := range keys {
+		seen[key]++
+	}
+	if !reflect.DeepEqual(seen, map[string]int{"MAP_KEY": 1, "DIR_KEY": 1}) {
+		t.Errorf("Keys() = %v", keys)
+	}
+
+	if _, err = NewReader(struct{ Source }{MapSource{}}).CheckUnknown("UMH_"); err == nil {
+		t.Error("CheckUnknown() error = nil for a source that cannot list its variables")
+	}
+}
@@ -0,0 +1,231 @@
+package env
+
+/*
+Copyright 2023 UMH Systems GmbH
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// validateTag holds the validation rules of a field, e.g. `validate:"min=1,max=65535"`.
+const validateTag = "validate"
+
+// validator is a single validation rule. check receives the raw value and the parsed value.
+type validator struct {
+	check func(raw string, value any) error
+	rule  string
+}
+
+// addValidator returns an Option appending a rule to the validators of a lookup.
+func addValidator(rule string, check func(raw string, value any) error) Option {
+	return func(o *options) {
+		o.validators = append(o.validators, validator{rule: rule, check: check})
+	}
+}
+
+// Min rejects numbers lower than limit. For strings, lists and maps, the length is checked instead.
+func Min(limit float64) Option {
+	return addValidator(fmt.Sprintf("min=%v", limit), func(_ string, value any) error {
+		n, err := measure(value)
+		if err != nil {
+			return err
+		}
+		if n < limit {
+			return fmt.Errorf("must be at least %v", limit)
+		}
+		return nil
+	})
+}
+
+// Max rejects numbers greater than limit. For strings, lists and maps, the length is checked instead.
+func Max(limit float64) Option {
+	return addValidator(fmt.Sprintf("max=%v", limit), func(_ string, value any) error {
+		n, err := measure(value)
+		if err != nil {
+			return err
+		}
+		if n > limit {
+			return fmt.Errorf("must be at most %v", limit)
+		}
+		return nil
+	})
+}
+
+// OneOf rejects raw values that are not in allowed.
+func OneOf(allowed ...string) Option {
+	return addValidator("oneof="+strings.Join(allowed, " "), func(raw string, _ any) error {
+		for _, a := range allowed {
+			if raw == a {
+				return nil
+			}
+		}
+		return fmt.Errorf("must be one of %s", strings.Join(allowed, ", "))
+	})
+}
+
+// Regex rejects raw values that do not match pattern.
+func Regex(pattern string) Option {
+	re, compileErr := regexp.Compile(pattern)
+	return addValidator("regex="+pattern, func(raw string, _ any) error {
+		if compileErr != nil {
+			return fmt.Errorf("invalid pattern: %w", compileErr)
+		}
+		if !re.MatchString(raw) {
+			return fmt.Errorf("must match %s", pattern)
+		}
+		return nil
+	})
+}
+
+// NonEmpty rejects values that are empty or only contain whitespace.
+func NonEmpty() Option {
+	return addValidator("nonempty", func(raw string, _ any) error {
+		if strings.TrimSpace(raw) == "" {
+			return errors.New("must not be empty")
+		}
+		return nil
+	})
+}
+
+// URL rejects values that are not absolute URLs with a scheme and a host.
+func URL() Option {
+	return addValidator("url", func(raw string, _ any) error {
+		u, err := url.Parse(raw)
+		if err != nil {
+			return err
+		}
+		if u.Scheme == "" || u.Host == "" {
+			return errors.New("must be an absolute URL with scheme and host")
+		}
+		return nil
+	})
+}
+
+// HostPort rejects values that are not of the form host:port with a valid port number.
+func HostPort() Option {
+	return addValidator("hostport", func(raw string, _ any) error {
+		_, port, err := net.SplitHostPort(raw)
+		if err != nil {
+			return err
+		}
+		if _, err = strconv.ParseUint(port, 10, 16); err != nil {
+			return fmt.Errorf("invalid port %q", port)
+		}
+		return nil
+	})
+}
+
+// FileExists rejects values that do not name an existing file or directory.
+func FileExists() Option {
+	return addValidator("file", func(raw string, _ any) error {
+		_, err := os.Stat(raw)
+		return err
+	})
+}
+
+// Validate adds a custom rule. fn receives the parsed value; it is an error if the value is not of type T.
+func Validate[T any](fn func(T) error) Option {
+	return addValidator("custom", func(_ string, value any) error {
+		v, ok := value.(T)
+		if !ok {
+			return fmt.Errorf("validator expects %s, got %T", typeName[T](), value)
+		}
+		return fn(v)
+	})
+}
+
+// validate runs the validators of o against the value of key.
+func validate(key, raw string, value any, o *options) error {
+	for _, v := range o.validators {
+		if err := v.check(raw, value); err != nil {
+			return &ValidationError{Key: key, Value: raw, Rule: v.rule, Err: err, Sensitive: o.sensitive}
+		}
+	}
+	return nil
+}
+
+// measure returns the number that Min and Max compare: the value of numbers and the length of strings, lists and
+// maps.
+func measure(value any) (float64, error) {
+	v := reflect.ValueOf(value)
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(v.Int()), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return float64(v.Uint()), nil
+	case reflect.Float32, reflect.Float64:
+		return v.Float(), nil
+	case reflect.String, reflect.Slice, reflect.Map, reflect.Array:
+		return float64(v.Len()), nil
+	default:
+		return 0, fmt.Errorf("cannot compare a value of type %T", value)
+	}
+}
+
+// parseValidateTag turns the rules of a validate tag into options. The rules are separated by commas; a regex rule
+// must come last, as it takes the rest of the tag, commas included.
+func parseValidateTag(tag string) ([]Option, error) {
+	var opts []Option
+	for tag = strings.TrimSpace(tag); tag != ""; tag = strings.TrimSpace(tag) {
+		var rule string
+		if strings.HasPrefix(tag, "regex=") {
+			rule, tag = tag, ""
+		} else {
+			rule, tag, _ = strings.Cut(tag, ",")
+		}
+
+		name, arg, _ := strings.Cut(strings.TrimSpace(rule), "=")
+		switch name {
+		case "min", "max":
+			n, err := strconv.ParseFloat(arg, 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid %s rule %q", name, rule)
+			}
+			if name == "min" {
+				opts = append(opts, Min(n))
+			} else {
+				opts = append(opts, Max(n))
+			}
+		case "oneof":
+			opts = append(opts, OneOf(strings.Fields(arg)...))
+		case "regex":
+			if _, err := regexp.Compile(arg); err != nil {
+				return nil, fmt.Errorf("invalid regex rule: %w", err)
+			}
+			opts = append(opts, Regex(arg))
+		case "nonempty":
+			opts = append(opts, NonEmpty())
+		case "url":
+			opts = append(opts, URL())
+		case "hostport":
+			opts = append(opts, HostPort())
+		case "file":
+			opts = append(opts, FileExists())
+		case "":
+		default:
+			return nil, fmt.Errorf("unknown validation rule %q", rule)
+		}
+	}
+	return opts, nil
+}
@@ -0,0 +1,138 @@
+package env
+
+/*
+Copyright 2023 UMH Systems GmbH
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+import (
+	"errors"
+	"os"
+	"testing"
+)
+
+// TestValidators tests the validation options
+func TestValidators(t *testing.T) {
+	t.Parallel()
+
+	file, err := os.CreateTemp(t.TempDir(), "exists")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = file.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	r := NewReader(MapSource{
+		"PORT":      "8080",
+		"BAD_PORT":  "70000",
+		"LEVEL":     "INFO",
+		"BAD_LEVEL": "VERBOSE",
+		"NAME":      "umh-core",
+		"BLANK":     "  ",
+		"URL":       "https://management.umh.app/api",
+		"BAD_URL":   "management.umh.app",
+		"BROKER":    "kafka:9092",
+		"BAD_HOST":  "kafka",
+		"FILE":      file.Name(),
+		"BAD_FILE":  file.Name() + ".missing",
+	})
+
+	tests := []struct {
+		name    string
+		key     string
+		opts    []Option
+		wantErr bool
+	}{
+		{name: "Case 1: String length within range", key: "PORT", opts: []Option{Min(1), Max(4)}},
+		{name: "Case 2: String length above max", key: "BAD_PORT", opts: []Option{Max(4)}, wantErr: true},
+		{name: "Case 3: String length below min", key: "PORT", opts: []Option{Min(5)}, wantErr: true},
+		{name: "Case 4: Value is one of the allowed values", key: "LEVEL", opts: []Option{OneOf("DEBUG", "INFO")}},
+		{name: "Case 5: Value is not one of the allowed values", key: "BAD_LEVEL", opts: []Option{OneOf("DEBUG", "INFO")}, wantErr: true},
+		{name: "Case 6: Value matches regex", key: "NAME", opts: []Option{Regex(`^[a-z-]+$`)}},
+		{name: "Case 7: Value does not match regex", key: "NAME", opts: []Option{Regex(`^[a-z]+$`)}, wantErr: true},
+		{name: "Case 8: Invalid regex", key: "NAME", opts: []Option{Regex(`(`)}, wantErr: true},
+		{name: "Case 9: Value is not empty", key: "NAME", opts: []Option{NonEmpty()}},
+		{name: "Case 10: Value is blank", key: "BLANK", opts: []Option{NonEmpty()}, wantErr: true},
+		{name: "Case 11: Value is a URL", key: "URL", opts: []Option{URL()}},
+		{name: "Case 12: Value is not an absolute URL", key: "BAD_URL", opts: []Option{URL()}, wantErr: true},
+		{name: "Case 13: Value is host:port", key: "BROKER", opts: []Option{HostPort()}},
+		{name: "Case 14: Value has no port", key: "BAD_HOST", opts: []Option{HostPort()}, wantErr: true},
+		{name: "Case 15: File exists", key: "FILE", opts: []Option{FileExists()}},
+		{name: "Case 16: File does not exist", key: "BAD_FILE", opts: []Option{FileExists()}, wantErr: true},
+		{name: "Case 17: Several validators pass", key: "NAME", opts: []Option{NonEmpty(), Min(3), Regex(`umh`)}},
+		{name: "Case 18: Custom validator fails", key: "NAME", opts: []Option{Validate(func(s string) error { return errors.New("nope") })}, wantErr: true},
+		{name: "Case 19: Custom validator with wrong type", key: "NAME", opts: []Option{Validate(func(int) error { return nil })}, wantErr: true},
+		{name: "Case 20: Unset variables are not validated", key: "NONEXISTENT", opts: []Option{NonEmpty()}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := r.GetAsString(tt.key, false, "fallback", tt.opts...)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("GetAsString() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			var validationErr *ValidationError
+			if tt.wantErr && !errors.As(err, &validationErr) {
+				t.Errorf("GetAsString() error = %v, want *ValidationError", err)
+			}
+		})
+	}
+}
+
+// TestValidatorsOnParsedValues tests that Min and Max compare parsed numbers and that violations return the fallback
+func TestValidatorsOnParsedValues(t *testing.T) {
+	t.Parallel()
+
+	r := NewReader(MapSource{"PORT": "8080", "BAD_PORT": "70000"})
+	if got, err := r.GetAsInt("PORT", false, 0, Min(1), Max(65535)); err != nil || got != 8080 {
+		t.Errorf("GetAsInt() = %d, %v, want 8080", got, err)
+	}
+	got, err := r.GetAsInt("BAD_PORT", false, 8080, Min(1), Max(65535))
+	var validationErr *ValidationError
+	if !errors.As(err, &validationErr) || validationErr.Rule != "max=65535" || got != 8080 {
+		t.Errorf("GetAsInt() = %d, %v, want fallback and *ValidationError for max=65535", got, err)
+	}
+}
+
+// TestLoadValidateTag tests the validate struct tag
+func TestLoadValidateTag(t *testing.T) {
+	t.Parallel()
+
+	type config struct {
+		Port  int    `env:"PORT" validate:"min=1,max=65535"`
+		Level string `env:"LEVEL" validate:"oneof=DEBUG INFO WARN"`
+		Name  string `env:"NAME" validate:"nonempty, regex=^[a-z]{1,3},[a-z]+$"`
+	}
+
+	var cfg config
+	r := NewReader(MapSource{"PORT": "8080", "LEVEL": "INFO", "NAME": "umh,core"})
+	if err := r.Load(&cfg); err != nil {
+		t.Errorf("Load() error = %v", err)
+	}
+
+	r = NewReader(MapSource{"PORT": "0", "LEVEL": "TRACE", "NAME": "UMH"})
+	var errs Errors
+	if err := r.Load(&cfg); !errors.As(err, &errs) || len(errs) != 3 {
+		t.Errorf("Load() error = %v, want 3 validation errors", err)
+	}
+
+	var invalid struct {
+		Port int `env:"PORT" validate:"between=1"`
+	}
+	if err := r.Load(&invalid); err == nil {
+		t.Error("Load() expected error for an unknown rule")
+	}
+}
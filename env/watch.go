@@ -0,0 +1,325 @@
+package env
+
+/*
+Copyright 2023 UMH Systems GmbH
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// defaultWatchInterval is used if WatchConfig.Interval is not set.
+const defaultWatchInterval = 5 * time.Second
+
+// WatchConfig describes the files a Watcher reads its configuration from.
+type WatchConfig struct {
+	// Source is consulted before the files, e.g. OSSource to let the environment of the process override them. It is
+	// not watched for changes. Files loaded with LoadDotenv should not be listed in Dotenv as well, as the copies
+	// in the environment would hide their changes.
+	Source Source
+	// Dirs are directories with one file per variable, like ConfigMaps and Secrets mounted into a pod. Earlier
+	// directories take precedence.
+	Dirs []string
+	// Dotenv are .env files. If several files define a variable, the last one wins.
+	Dotenv []string
+	// Interval is how often the files are checked for changes. The default is 5 seconds.
+	Interval time.Duration
+}
+
+// Change is a variable whose value differs between two configurations. The values are formatted like in a Snapshot,
+// so sensitive values and those read from a KEY_FILE are redacted.
+type Change struct {
+	// Key is the name of the variable.
+	Key string
+	// Old is the previous value.
+	Old string
+	// New is the current value.
+	New string
+}
+
+// Update is sent to the channels registered with Watcher.Notify when the configuration changes.
+type Update[T any] struct {
+	// Old is the previous configuration.
+	Old T
+	// New is the current configuration.
+	New T
+	// Changes lists the variables that changed, ordered as the fields of T.
+	Changes []Change
+}
+
+// Watcher keeps a configuration struct of type T up to date with the files described by a WatchConfig. T is loaded
+// like Load does, and if *T has a `Validate() error` method, it is called as well. When the files change, T is
+// loaded again and subscribers are notified; if the new configuration is invalid, the last good one is kept and the
+// reason is logged.
+//
+//	w, err := env.NewWatcher[Config](env.WatchConfig{Source: env.OSSource{}, Dirs: []string{"/etc/config"}})
+//	if err != nil {
+//		return err
+//	}
+//	w.Subscribe(func(old, current Config, changes []env.Change) {
+//		level.SetLevel(current.LogLevel)
+//	})
+//	go w.Run(ctx)
+type Watcher[T any] struct {
+	config WatchConfig
+	opts   []Option
+	logger *zap.SugaredLogger
+
+	// reload serializes reloads, so that subscribers see updates in order.
+	reload sync.Mutex
+	// stamp describes the watched files as of the last reload, see fingerprint.
+	stamp string
+	// files are the files named by KEY_FILE variables in the last load, which are watched as well.
+	files []string
+	// fromFile holds the variables that have been read from a KEY_FILE, whose changes are redacted.
+	fromFile map[string]bool
+
+	mu          sync.RWMutex
+	current     T
+	subscribers []func(old, current T, changes []Change)
+	channels    []chan<- Update[T]
+}
+
+// NewWatcher loads the initial configuration and returns a Watcher for it. The options are applied to every
+// lookup. An error is returned if the initial configuration is invalid, as there is no good one to fall back to.
+func NewWatcher[T any](config WatchConfig, opts ...Option) (*Watcher[T], error) {
+	if config.Interval <= 0 {
+		config.Interval = defaultWatchInterval
+	}
+	w := &Watcher[T]{config: config, opts: opts, logger: newOptions(opts).log(), fromFile: make(map[string]bool)}
+	w.stamp = w.fingerprint()
+	current, err := w.load()
+	if err != nil {
+		return nil, err
+	}
+	w.current = current
+	return w, nil
+}
+
+// Current returns the last good configuration.
+func (w *Watcher[T]) Current() T {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	return w.current
+}
+
+// Subscribe calls fn after every change of the configuration, from the goroutine that reloaded it. It returns a
+// function that cancels the subscription.
+func (w *Watcher[T]) Subscribe(fn func(old, current T, changes []Change)) (cancel func()) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.subscribers = append(w.subscribers, fn)
+	index := len(w.subscribers) - 1
+	return func() {
+		w.mu.Lock()
+		defer w.mu.Unlock()
+		w.subscribers[index] = nil
+	}
+}
+
+// Notify sends an Update to ch after every change of the configuration. Like signal.Notify, the Watcher does not
+// block sending to ch, so updates are dropped if ch has no buffer space left.
+func (w *Watcher[T]) Notify(ch chan<- Update[T]) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.channels = append(w.channels, ch)
+}
+
+// Run checks the files for changes at the configured interval and reloads the configuration when they change, until
+// ctx is done.
+func (w *Watcher[T]) Run(ctx context.Context) {
+	ticker := time.NewTicker(w.config.Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			w.reload.Lock()
+			changed := w.fingerprint() != w.stamp
+			w.reload.Unlock()
+			if changed {
+				// The error is logged by Reload
+				_ = w.Reload()
+			}
+		}
+	}
+}
+
+// Reload loads the configuration now, whether the files changed or not, and notifies the subscribers if it differs
+// from the current one. If the new configuration is invalid, the current one is kept and the error is returned.
+func (w *Watcher[T]) Reload() error {
+	w.reload.Lock()
+	defer w.reload.Unlock()
+
+	w.stamp = w.fingerprint()
+	next, err := w.load()
+	if err != nil {
+		w.logger.Errorw("Invalid configuration, keeping the last good one", "error", err)
+		return err
+	}
+
+	w.mu.Lock()
+	old := w.current
+	w.current = next
+	subscribers := append([]func(old, current T, changes []Change){}, w.subscribers...)
+	channels := append([]chan<- Update[T]{}, w.channels...)
+	w.mu.Unlock()
+
+	changes := diffStruct(reflect.ValueOf(old), reflect.ValueOf(next), w.opts, w.fromFile, nil)
+	if len(changes) == 0 {
+		return nil
+	}
+	w.logger.Infow("Configuration changed", "changes", len(changes))
+	for _, fn := range subscribers {
+		if fn != nil {
+			fn(old, next, changes)
+		}
+	}
+	for _, ch := range channels {
+		select {
+		case ch <- Update[T]{Old: old, New: next, Changes: changes}:
+		default:
+		}
+	}
+	return nil
+}
+
+// load reads a fresh configuration from the watched files and validates it. It records the files named by KEY_FILE
+// variables, even if the configuration is invalid, so that fixing them triggers a reload. As the stamp was taken
+// before, a newly named file causes one more reload, which finds no changes.
+func (w *Watcher[T]) load() (T, error) {
+	var cfg T
+	source, err := w.source()
+	if err != nil {
+		return cfg, err
+	}
+	r := NewReader(source, w.opts...)
+	err = r.Load(&cfg)
+	w.files = w.files[:0]
+	for _, resolved := range r.Snapshot() {
+		if resolved.Origin == OriginFile {
+			w.files = append(w.files, resolved.Path)
+			w.fromFile[resolved.Key] = true
+		}
+	}
+	if err != nil {
+		return cfg, err
+	}
+	if v, ok := any(&cfg).(interface{ Validate() error }); ok {
+		if err = v.Validate(); err != nil {
+			return cfg, fmt.Errorf("invalid configuration: %w", err)
+		}
+	}
+	return cfg, nil
+}
+
+// source returns the sources of the configuration in order of precedence, with the .env files read anew.
+func (w *Watcher[T]) source() (Source, error) {
+	var chain ChainSource
+	if w.config.Source != nil {
+		chain = append(chain, w.config.Source)
+	}
+	for _, dir := range w.config.Dirs {
+		chain = append(chain, DirSource{Path: dir})
+	}
+	if len(w.config.Dotenv) > 0 {
+		values, err := ReadDotenv(w.config.Dotenv...)
+		if err != nil {
+			return nil, err
+		}
+		chain = append(chain, MapSource(values))
+	}
+	return chain, nil
+}
+
+// fingerprint describes the name, size and modification time of every watched file, including the files named by
+// KEY_FILE variables in the last load. Symbolic links are followed, so that the swap of the ..data link Kubernetes
+// uses to update mounted volumes changes the fingerprint.
+func (w *Watcher[T]) fingerprint() string {
+	var b strings.Builder
+	stat := func(path string) {
+		info, err := os.Stat(path)
+		if err != nil {
+			fmt.Fprintf(&b, "%s: %v\n", path, err)
+			return
+		}
+		fmt.Fprintf(&b, "%s: %d %d\n", path, info.Size(), info.ModTime().UnixNano())
+	}
+
+	for _, dir := range w.config.Dirs {
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			fmt.Fprintf(&b, "%s: %v\n", dir, err)
+			continue
+		}
+		for _, entry := range entries {
+			stat(filepath.Join(dir, entry.Name()))
+		}
+	}
+	for _, path := range w.config.Dotenv {
+		stat(path)
+	}
+	for _, path := range w.files {
+		stat(path)
+	}
+	return b.String()
+}
+
+// diffStruct appends the variables of the env-tagged fields that differ between old and current to changes, recursing
+// into untagged nested structs like Load does. The values are formatted as the lookups with opts read them, and
+// redacted if the field is sensitive or its variable is in fromFile.
+func diffStruct(old, current reflect.Value, opts []Option, fromFile map[string]bool, changes []Change) []Change {
+	t := old.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		tag, tagged := field.Tag.Lookup(envTag)
+		if !tagged {
+			if field.Type.Kind() == reflect.Struct {
+				changes = diffStruct(old.Field(i), current.Field(i), opts, fromFile, changes)
+			}
+			continue
+		}
+		before, after := old.Field(i).Interface(), current.Field(i).Interface()
+		if reflect.DeepEqual(before, after) {
+			continue
+		}
+		// The tag was checked when the configuration was loaded
+		f, _ := parseEnvTag(tag)
+		fieldOpts := append(append([]Option{}, opts...), f.opts...)
+		if splitsElements(field.Type) {
+			fieldOpts = append(fieldOpts, splitting())
+		}
+		o := newOptions(fieldOpts)
+		change := Change{Key: f.key, Old: formatValue(before, o), New: formatValue(after, o)}
+		if o.sensitive || fromFile[f.key] {
+			change.Old, change.New = redacted, redacted
+		}
+		changes = append(changes, change)
+	}
+	return changes
+}
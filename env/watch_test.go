@@ -0,0 +1,241 @@
+package env
+
+/*
+Copyright 2023 UMH Systems GmbH
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+type watchTestConfig struct {
+	Topics   []string `env:"WATCH_TOPICS" default:"[\"a\"]"`
+	LogLevel string   `env:"WATCH_LOG_LEVEL" default:"INFO" validate:"oneof=DEBUG INFO WARN"`
+	Password string   `env:"WATCH_PASSWORD,sensitive"`
+	Kafka    struct {
+		Brokers string `env:"WATCH_KAFKA_BROKERS,required"`
+	}
+}
+
+// Validate rejects configurations without topics.
+func (c *watchTestConfig) Validate() error {
+	if len(c.Topics) == 0 {
+		return errors.New("at least one topic is required")
+	}
+	return nil
+}
+
+// writeWatchFile writes content to path and moves its modification time forward, so that the change is noticed
+// even on file systems with a coarse time resolution.
+func writeWatchFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	stamp := time.Now().Add(time.Duration(len(content)) * time.Second)
+	if err := os.Chtimes(path, stamp, stamp); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// TestWatcherReload tests that reloads notify subscribers with a diff and that invalid configurations are rejected
+func TestWatcherReload(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	dotenv := filepath.Join(t.TempDir(), ".env")
+	brokers := "WATCH_KAFKA_BROKERS=localhost:9092\nWATCH_LOG_LEVEL=WARN\n"
+	writeWatchFile(t, filepath.Join(dir, "WATCH_LOG_LEVEL"), "INFO\n")
+	writeWatchFile(t, filepath.Join(dir, "WATCH_TOPICS"), `["a","b"]`)
+	writeWatchFile(t, filepath.Join(dir, "WATCH_PASSWORD"), "old-secret\n")
+	writeWatchFile(t, dotenv, brokers)
+
+	core, logs := observer.New(zapcore.InfoLevel)
+	w, err := NewWatcher[watchTestConfig](WatchConfig{Dirs: []string{dir}, Dotenv: []string{dotenv}}, WithLogger(zap.New(core).Sugar()))
+	if err != nil {
+		t.Fatalf("NewWatcher() error = %v", err)
+	}
+	if got := w.Current(); got.LogLevel != "INFO" || got.Kafka.Brokers != "localhost:9092" || len(got.Topics) != 2 {
+		t.Fatalf("Current() = %+v", got)
+	}
+
+	var calls [][]Change
+	cancel := w.Subscribe(func(old, current watchTestConfig, changes []Change) {
+		calls = append(calls, changes)
+	})
+	updates := make(chan Update[watchTestConfig], 1)
+	w.Notify(updates)
+
+	tests := []struct {
+		name    string
+		file    string
+		content string
+		// restore is written to file after a failed reload, so that the next case starts from a valid configuration.
+		restore string
+		want    []Change
+		wantErr bool
+	}{
+		{name: "Case 1: Unchanged files do not notify", want: nil},
+		{
+			name: "Case 2: Changes are reported", file: filepath.Join(dir, "WATCH_LOG_LEVEL"), content: "DEBUG",
+			want: []Change{{Key: "WATCH_LOG_LEVEL", Old: "INFO", New: "DEBUG"}},
+		},
+		{name: "Case 3: Invalid values keep the last good configuration", file: filepath.Join(dir, "WATCH_LOG_LEVEL"), content: "LOUD", restore: "DEBUG", wantErr: true},
+		{name: "Case 4: Failed validation keeps the last good configuration", file: filepath.Join(dir, "WATCH_TOPICS"), content: "[]", restore: `["a","b"]`, wantErr: true},
+		{name: "Case 5: Missing required variables keep the last good configuration", file: dotenv, content: "WATCH_LOG_LEVEL=WARN\n", restore: brokers, wantErr: true},
+		{
+			name: "Case 6: Sensitive values are redacted", file: filepath.Join(dir, "WATCH_PASSWORD"), content: "new-secret",
+			want: []Change{{Key: "WATCH_PASSWORD", Old: redacted, New: redacted}},
+		},
+		{
+			name: "Case 7: Values from .env files are reloaded", file: dotenv, content: "WATCH_KAFKA_BROKERS=kafka:9092\n",
+			want: []Change{{Key: "WATCH_KAFKA_BROKERS", Old: "localhost:9092", New: "kafka:9092"}},
+		},
+	}
+
+	// Each case starts from the files of the previous one, so they run in order
+	for _, tt := range tests {
+		if tt.file != "" {
+			writeWatchFile(t, tt.file, tt.content)
+		}
+		before := w.Current()
+		calls = nil
+		err = w.Reload()
+		if (err != nil) != tt.wantErr {
+			t.Fatalf("%s: Reload() error = %v, wantErr %v", tt.name, err, tt.wantErr)
+		}
+		if tt.wantErr {
+			if got := w.Current(); !reflect.DeepEqual(got, before) {
+				t.Errorf("%s: Current() = %+v, want %+v", tt.name, got, before)
+			}
+			writeWatchFile(t, tt.file, tt.restore)
+			continue
+		}
+
+		var got []Change
+		if len(calls) == 1 {
+			got = calls[0]
+		} else if len(calls) > 1 {
+			t.Errorf("%s: subscriber called %d times", tt.name, len(calls))
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: changes = %+v, want %+v", tt.name, got, tt.want)
+		}
+		select {
+		case update := <-updates:
+			if !reflect.DeepEqual(update.Changes, tt.want) || reflect.DeepEqual(update.Old, update.New) {
+				t.Errorf("%s: update = %+v", tt.name, update)
+			}
+		default:
+			if tt.want != nil {
+				t.Errorf("%s: no update sent", tt.name)
+			}
+		}
+	}
+
+	if n := logs.FilterMessage("Invalid configuration, keeping the last good one").Len(); n != 3 {
+		t.Errorf("logged %d invalid configurations, want 3", n)
+	}
+
+	cancel()
+	writeWatchFile(t, filepath.Join(dir, "WATCH_LOG_LEVEL"), "WARN")
+	calls = nil
+	if err = w.Reload(); err != nil || len(calls) != 0 {
+		t.Errorf("Reload() after cancel = %v, %d calls", err, len(calls))
+	}
+}
+
+// TestWatcherRun tests that Run picks up changed files
+func TestWatcherRun(t *testing.T) {
+	t.Parallel()
+
+	dotenv := filepath.Join(t.TempDir(), ".env")
+	writeWatchFile(t, dotenv, "WATCH_KAFKA_BROKERS=localhost:9092\n")
+	w, err := NewWatcher[watchTestConfig](WatchConfig{Dotenv: []string{dotenv}, Interval: 10 * time.Millisecond}, WithLogger(zap.NewNop().Sugar()))
+	if err != nil {
+		t.Fatalf("NewWatcher() error = %v", err)
+	}
+	updates := make(chan Update[watchTestConfig], 1)
+	w.Notify(updates)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go w.Run(ctx)
+
+	writeWatchFile(t, dotenv, "WATCH_KAFKA_BROKERS=kafka:9092\n")
+	select {
+	case update := <-updates:
+		if update.Old.Kafka.Brokers != "localhost:9092" || update.New.Kafka.Brokers != "kafka:9092" {
+			t.Errorf("update = %+v", update)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("no update after the file changed")
+	}
+}
+
+// TestWatcherRunFileVariable tests that the files named by KEY_FILE variables are watched
+func TestWatcherRunFileVariable(t *testing.T) {
+	t.Parallel()
+
+	brokers := filepath.Join(t.TempDir(), "brokers")
+	dotenv := filepath.Join(t.TempDir(), ".env")
+	writeWatchFile(t, brokers, "localhost:9092\n")
+	writeWatchFile(t, dotenv, "WATCH_KAFKA_BROKERS_FILE="+brokers+"\n")
+	w, err := NewWatcher[watchTestConfig](WatchConfig{Dotenv: []string{dotenv}, Interval: 10 * time.Millisecond}, WithLogger(zap.NewNop().Sugar()))
+	if err != nil {
+		t.Fatalf("NewWatcher() error = %v", err)
+	}
+	updates := make(chan Update[watchTestConfig], 1)
+	w.Notify(updates)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go w.Run(ctx)
+
+	writeWatchFile(t, brokers, "kafka:9092\n")
+	select {
+	case update := <-updates:
+		if update.Old.Kafka.Brokers != "localhost:9092" || update.New.Kafka.Brokers != "kafka:9092" {
+			t.Errorf("update = %+v", update)
+		}
+		if want := []Change{{Key: "WATCH_KAFKA_BROKERS", Old: redacted, New: redacted}}; !reflect.DeepEqual(update.Changes, want) {
+			t.Errorf("changes = %+v, want the values read from the file to be redacted", update.Changes)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("no update after the file named by WATCH_KAFKA_BROKERS_FILE changed")
+	}
+}
+
+// TestWatcherInvalidStart tests that the initial configuration must be valid
+func TestWatcherInvalidStart(t *testing.T) {
+	t.Parallel()
+
+	if _, err := NewWatcher[watchTestConfig](WatchConfig{Source: MapSource{}}); !errors.Is(err, ErrNotSet) {
+		t.Errorf("NewWatcher() error = %v, want ErrNotSet", err)
+	}
+	if _, err := NewWatcher[watchTestConfig](WatchConfig{Dotenv: []string{filepath.Join(t.TempDir(), "missing.env")}}); err == nil {
+		t.Error("NewWatcher() error = nil, want an error for a missing .env file")
+	}
+}
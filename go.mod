@@ -3,9 +3,11 @@ module github.com/united-manufacturing-hub/umh-utils
 go 1.19
 
 require (
+	github.com/BurntSushi/toml v1.3.2
 	go.elastic.co/ecszap v1.0.2
 	go.uber.org/zap v1.25.0
 	gopkg.in/inf.v0 v0.9.1
+	gopkg.in/yaml.v3 v3.0.1
 )
 
 require (